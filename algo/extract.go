@@ -1,6 +1,7 @@
 package algo
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -14,6 +15,10 @@ const (
 
 var listIndexRegexMatcher = regexp.MustCompile(`^([\w-]*)(\[([0-9]+)\])+$`)
 
+// ErrInvalidPath is returned by LookupValInSource for keys that can not be
+// parsed or do not fit the source, e.g. a key used on a list.
+var ErrInvalidPath = errors.New("invalid path")
+
 // common function to extract any key from source
 // example: source -> map, key-> k[0][1].k1
 // example source -> list, key -> [0][1]
@@ -81,7 +86,10 @@ func getMapKeyValue(m interface{}, key string) (interface{}, error) {
 	if v.Kind() != reflect.Map {
 		return nil, fmt.Errorf("%v is not a map", v.Kind())
 	}
-	keyValue := reflect.ValueOf(key)
+	keyValue, err := mapKey(v, key)
+	if err != nil {
+		return nil, err
+	}
 	mapValue := v.MapIndex(keyValue)
 	if !mapValue.IsValid() {
 		return nil, fmt.Errorf("inavlid value: %v", mapValue)
@@ -90,6 +98,21 @@ func getMapKeyValue(m interface{}, key string) (interface{}, error) {
 	return mapValue.Interface(), nil
 }
 
+// mapKey converts key to the key type of the map m, so maps with a named
+// string key type can be looked up too.
+func mapKey(m reflect.Value, key string) (reflect.Value, error) {
+	keyType := m.Type().Key()
+	switch keyType.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(key).Convert(keyType), nil
+	case reflect.Interface:
+		if reflect.TypeOf(key).Implements(keyType) {
+			return reflect.ValueOf(key), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("%v keys are not strings", m.Type())
+}
+
 func getArrayIndexValue(arr any, idx int, missingKeyError bool) (any, error) {
 	v := reflect.ValueOf(arr)
 	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
@@ -163,3 +186,201 @@ func getListIndexValue(source interface{}, indexes []int, missingKeyError bool)
 	}
 	return source, nil
 }
+
+// LookupValInSource behaves like GetValFromSource but tells a missing value
+// apart from an invalid key. found is false when a key is not present, an
+// index is past the end of a list or a value on the way is null; keys that
+// can not be parsed or do not fit source fail with ErrInvalidPath.
+func LookupValInSource(source interface{}, keyStr string) (val interface{}, found bool, err error) {
+	if keyStr == "" {
+		return source, true, nil
+	}
+
+	for _, item := range strings.Split(keyStr, ".") {
+		key, indexes, err := extractKeyIndex(item)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+		}
+		if source == nil {
+			return nil, false, nil
+		}
+
+		v := reflect.ValueOf(source)
+		switch v.Kind() {
+		case reflect.Map:
+			keyValue, err := mapKey(v, key)
+			if err != nil {
+				return nil, false, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+			}
+			mapValue := v.MapIndex(keyValue)
+			if !mapValue.IsValid() {
+				return nil, false, nil
+			}
+			source = mapValue.Interface()
+		case reflect.Slice, reflect.Array, reflect.String:
+			if key != "" {
+				return nil, false, fmt.Errorf("%w: key used on list item %v", ErrInvalidPath, item)
+			}
+		default:
+			return nil, false, fmt.Errorf("%w: key %v used on a %v", ErrInvalidPath, item, v.Kind())
+		}
+
+		for _, index := range indexes {
+			if source == nil {
+				return nil, false, nil
+			}
+			v := reflect.ValueOf(source)
+			switch v.Kind() {
+			case reflect.Slice, reflect.Array:
+				if v.Len() <= index {
+					return nil, false, nil
+				}
+				source = v.Index(index).Interface()
+			case reflect.String:
+				if v.Len() <= index {
+					return nil, false, nil
+				}
+				source = string(v.String()[index])
+			default:
+				return nil, false, fmt.Errorf("%w: index %v used on a %v", ErrInvalidPath, index, v.Kind())
+			}
+		}
+	}
+	return source, true, nil
+}
+
+// pathStep is one step of a key: a map key, or a list index when index is
+// not negative.
+type pathStep struct {
+	key   string
+	index int
+}
+
+// pathSteps splits keyStr into the steps GetValFromSource takes.
+func pathSteps(keyStr string) ([]pathStep, error) {
+	steps := make([]pathStep, 0)
+	for _, item := range strings.Split(keyStr, ".") {
+		key, indexes, err := extractKeyIndex(item)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, pathStep{key: key, index: -1})
+		for _, index := range indexes {
+			steps = append(steps, pathStep{index: index})
+		}
+	}
+	return steps, nil
+}
+
+// common function to replace the value of an existing key in source
+// maps and lists are modified in place, the key must already be present
+// example: source -> map, key-> k[0][1].k1
+// example source -> list, key -> [0][1]
+func SetValInSource(source interface{}, keyStr string, val interface{}) error {
+	if keyStr == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	if source != nil && reflect.TypeOf(source).Kind() == reflect.Array {
+		return fmt.Errorf("array source can not be modified in place")
+	}
+
+	steps, err := pathSteps(keyStr)
+	if err != nil {
+		return err
+	}
+	_, err = setValue(reflect.ValueOf(source), steps, val)
+	return err
+}
+
+// setValue sets val at steps below v and returns v, or the modified copy of v
+// when v is an array, which can not be changed in place. The caller stores
+// the returned value back in the parent of v.
+func setValue(v reflect.Value, steps []pathStep, val interface{}) (reflect.Value, error) {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return reflect.Value{}, fmt.Errorf("source must not be nil")
+	}
+
+	step := steps[0]
+	if step.index >= 0 {
+		return setArrayIndexValue(v, step.index, steps[1:], val)
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		return setMapKeyValue(v, step.key, steps[1:], val)
+	case reflect.Slice, reflect.Array, reflect.String:
+		if step.key != "" {
+			return reflect.Value{}, fmt.Errorf("key used on list item %v", step.key)
+		}
+		if len(steps) == 1 {
+			return reflect.Value{}, fmt.Errorf("key must not be empty")
+		}
+		return setValue(v, steps[1:], val)
+	}
+	return reflect.Value{}, fmt.Errorf("inavlid usage of %v key non map/list", step.key)
+}
+
+func setMapKeyValue(m reflect.Value, key string, steps []pathStep, val interface{}) (reflect.Value, error) {
+	keyValue, err := mapKey(m, key)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	mapValue := m.MapIndex(keyValue)
+	if !mapValue.IsValid() {
+		return reflect.Value{}, fmt.Errorf("key %v is not present", key)
+	}
+	newValue, err := setChild(mapValue, m.Type().Elem(), steps, val)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	m.SetMapIndex(keyValue, newValue)
+	return m, nil
+}
+
+func setArrayIndexValue(arr reflect.Value, idx int, steps []pathStep, val interface{}) (reflect.Value, error) {
+	switch arr.Kind() {
+	case reflect.Slice:
+	case reflect.Array:
+		// arrays are values: change a copy and hand it back to the parent
+		copied := reflect.New(arr.Type()).Elem()
+		copied.Set(arr)
+		arr = copied
+	default:
+		return reflect.Value{}, fmt.Errorf("%v is not a list", arr.Kind())
+	}
+	if arr.Len() <= idx {
+		return reflect.Value{}, fmt.Errorf("index out of bound %v", idx)
+	}
+	newValue, err := setChild(arr.Index(idx), arr.Type().Elem(), steps, val)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	arr.Index(idx).Set(newValue)
+	return arr, nil
+}
+
+// setChild returns the value that replaces child, of type typ: val at the end
+// of the key, or child with val set at the remaining steps.
+func setChild(child reflect.Value, typ reflect.Type, steps []pathStep, val interface{}) (reflect.Value, error) {
+	if len(steps) == 0 {
+		return assignableValue(val, typ)
+	}
+	newValue, err := setValue(child, steps, val)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return newValue, nil
+}
+
+func assignableValue(val any, typ reflect.Type) (reflect.Value, error) {
+	if val == nil {
+		return reflect.Zero(typ), nil
+	}
+	newValue := reflect.ValueOf(val)
+	if !newValue.Type().AssignableTo(typ) {
+		return reflect.Value{}, fmt.Errorf("value of type %v can not be assigned to %v", newValue.Type(), typ)
+	}
+	return newValue, nil
+}
@@ -1,12 +1,15 @@
 package algo
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+type fieldName string
+
 type wantT struct {
 	res any
 	err error
@@ -44,3 +47,112 @@ func TestGetValFromSource(t *testing.T) {
 		})
 	}
 }
+
+func TestSetValInSource(t *testing.T) {
+	tests := []struct {
+		source  any
+		keyStr  string
+		val     any
+		want    any
+		wantErr bool
+	}{
+		{
+			source: map[string]any{"name": "ram"}, keyStr: "name", val: "shyam",
+			want: map[string]any{"name": "shyam"},
+		},
+		{
+			source: map[string]any{"data": map[string]any{"name": "ram"}}, keyStr: "data.name", val: "shyam",
+			want: map[string]any{"data": map[string]any{"name": "shyam"}},
+		},
+		{
+			source: map[string]any{"data": []string{"name", "ram"}}, keyStr: "data[1]", val: "shyam",
+			want: map[string]any{"data": []string{"name", "shyam"}},
+		},
+		{
+			source: map[string]any{"k": []any{[]any{map[string]any{"k1": 1}}}}, keyStr: "k[0][0].k1", val: 2,
+			want: map[string]any{"k": []any{[]any{map[string]any{"k1": 2}}}},
+		},
+		{
+			source: []any{[]any{"a", "b"}}, keyStr: "[0][1]", val: "c",
+			want: []any{[]any{"a", "c"}},
+		},
+		{
+			source: map[string]any{"name": "ram"}, keyStr: "age", val: 1,
+			want: map[string]any{"name": "ram"}, wantErr: true,
+		},
+		{
+			source: map[string]any{"data": []string{"name"}}, keyStr: "data[1]", val: "ram",
+			want: map[string]any{"data": []string{"name"}}, wantErr: true,
+		},
+		{
+			source: map[string]any{"data": []string{"name"}}, keyStr: "data[0]", val: 1,
+			want: map[string]any{"data": []string{"name"}}, wantErr: true,
+		},
+		{
+			source: map[string]any{"name": "ram"}, keyStr: "name.first", val: "shyam",
+			want: map[string]any{"name": "ram"}, wantErr: true,
+		},
+		{
+			source: map[string]any{"name": "ram"}, keyStr: "", val: "shyam",
+			want: map[string]any{"name": "ram"}, wantErr: true,
+		},
+		{
+			source: map[fieldName]any{"name": "ram"}, keyStr: "name", val: "shyam",
+			want: map[fieldName]any{"name": "shyam"},
+		},
+		{
+			source: map[string]any{"data": [2]string{"name", "ram"}}, keyStr: "data[1]", val: "shyam",
+			want: map[string]any{"data": [2]string{"name", "shyam"}},
+		},
+		{
+			source: map[string]any{"data": []any{[1]any{map[string]any{"k1": 1}}}}, keyStr: "data[0][0].k1", val: 2,
+			want: map[string]any{"data": []any{[1]any{map[string]any{"k1": 2}}}},
+		},
+		{
+			source: map[int]any{1: "ram"}, keyStr: "1", val: "shyam",
+			want: map[int]any{1: "ram"}, wantErr: true,
+		},
+	}
+
+	for id, test := range tests {
+		t.Run(fmt.Sprintf("%v", id), func(t *testing.T) {
+			err := SetValInSource(test.source, test.keyStr, test.val)
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.want, test.source)
+		})
+	}
+}
+
+func TestLookupValInSource(t *testing.T) {
+	tests := []struct {
+		source    any
+		keyStr    string
+		want      any
+		wantFound bool
+		wantErr   bool
+	}{
+		{source: map[string]any{"name": "ram"}, keyStr: "name", want: "ram", wantFound: true},
+		{source: map[string]any{"name": nil}, keyStr: "name", want: nil, wantFound: true},
+		{source: map[string]any{"data": []string{"name", "ram"}}, keyStr: "data[1]", want: "ram", wantFound: true},
+		{source: map[string]any{"data": [2]string{"name", "ram"}}, keyStr: "data[1]", want: "ram", wantFound: true},
+		{source: map[fieldName]any{"name": "ram"}, keyStr: "name", want: "ram", wantFound: true},
+		{source: map[string]any{"user name": "ram"}, keyStr: "user name", wantFound: false},
+		{source: map[string]any{"e$[0]": "ram"}, keyStr: "e$[0]", want: "ram", wantFound: true},
+		{source: map[string]any{"name": "ram"}, keyStr: "age", wantFound: false},
+		{source: map[string]any{"data": nil}, keyStr: "data.name", wantFound: false},
+		{source: map[string]any{"data": []string{"name"}}, keyStr: "data[1]", wantFound: false},
+		{source: map[string]any{"data": []string{"name"}}, keyStr: "data.name", wantErr: true},
+		{source: map[string]any{"data": 1}, keyStr: "data[0]", wantErr: true},
+		{source: map[string]any{"data": 1}, keyStr: "data.name", wantErr: true},
+		{source: map[int]any{1: "ram"}, keyStr: "1", wantErr: true},
+	}
+
+	for id, test := range tests {
+		t.Run(fmt.Sprintf("%v", id), func(t *testing.T) {
+			val, found, err := LookupValInSource(test.source, test.keyStr)
+			assert.Equal(t, test.wantErr, errors.Is(err, ErrInvalidPath))
+			assert.Equal(t, test.wantFound, found)
+			assert.Equal(t, test.want, val)
+		})
+	}
+}
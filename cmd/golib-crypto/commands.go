@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nected/go-lib/crypto"
+	"github.com/nected/go-lib/crypto/aes"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// encryptModes maps the -mode flag of encrypt to the encryption function.
+var encryptModes = map[string]func(keyName string, data []byte) (*models.Payload, error){
+	"rsa":           crypto.EncryptRSA,
+	"envelope":      crypto.EncryptRSAEnvelope,
+	"aes":           crypto.EncryptAESNamed,
+	"deterministic": crypto.EncryptAESDeterministic,
+}
+
+func runEncrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("encrypt", stderr)
+	var keys keyFlags
+	keys.register(fs)
+	mode := fs.String("mode", "rsa", "`mode`: rsa, envelope, aes (named secret) or deterministic (AES-SIV)")
+	in := fs.String("in", "", "read the value from `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	encrypt, ok := encryptModes[*mode]
+	if !ok {
+		return fmt.Errorf("unknown mode %q", *mode)
+	}
+	if keys.name == "" {
+		return fmt.Errorf("-key is required")
+	}
+	value, err := readValue(fs, *in, stdin, false)
+	if err != nil {
+		return err
+	}
+	if err := keys.load(stderr); err != nil {
+		return err
+	}
+
+	payload, err := encrypt(keys.name, value)
+	if err != nil {
+		return err
+	}
+	// rsa.Encrypt passes data through when the key is not loaded, which must
+	// not end up as a "ciphertext"
+	if payload.KeyVersion == 0 && !payload.AlreadyEncrypted {
+		return &errors.KeyError{KeyName: keys.name, Err: errors.ErrKeyNotFound}
+	}
+	_, err = fmt.Fprintln(stdout, payload.String())
+	return err
+}
+
+func runDecrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("decrypt", stderr)
+	var keys keyFlags
+	keys.register(fs)
+	in := fs.String("in", "", "read the value from `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	value, err := readValue(fs, *in, stdin, true)
+	if err != nil {
+		return err
+	}
+	if err := keys.load(stderr); err != nil {
+		return err
+	}
+
+	payload, err := crypto.DecryptStrict(string(value))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, payload.Data)
+	return err
+}
+
+func runInspect(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("inspect", stderr)
+	var keys keyFlags
+	keys.register(fs)
+	in := fs.String("in", "", "read the value from `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	value, err := readValue(fs, *in, stdin, true)
+	if err != nil {
+		return err
+	}
+	parsed := models.ParsePayload(string(value))
+	if parsed == nil {
+		return errors.ErrMalformedHeader
+	}
+	if err := keys.load(stderr); err != nil {
+		return err
+	}
+
+	store := models.DefaultKeyStore()
+	if aes.IsNamedKeyType(parsed.KeyType) || parsed.KeyType == models.KeyTypeBlindIndex {
+		store = models.DefaultSecretStore()
+	}
+	keyStatus := "not loaded"
+	if keyInfo := models.LookupKey(store, parsed.KeyName, parsed.KeyVersion); keyInfo != nil {
+		keyStatus = keyInfo.GetState().String()
+	}
+	_, err = fmt.Fprintf(stdout, "name:    %s\nversion: %d\ntype:    %s\nkey:     %s\n",
+		parsed.KeyName, parsed.KeyVersion, parsed.KeyType, keyStatus)
+	return err
+}
+
+func runRotate(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rotate", stderr)
+	var keys keyFlags
+	keys.register(fs)
+	in := fs.String("in", "", "read the value from `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	value, err := readValue(fs, *in, stdin, true)
+	if err != nil {
+		return err
+	}
+	if models.ParsePayload(string(value)) == nil {
+		return errors.ErrMalformedHeader
+	}
+	if err := keys.load(stderr); err != nil {
+		return err
+	}
+
+	rotated, ok, err := crypto.Rotate(string(value))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(stderr, "already encrypted with the latest key version")
+	}
+	_, err = fmt.Fprintln(stdout, rotated)
+	return err
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/config"
+)
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+func runKeygen(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("keygen", stderr)
+	keyType := fs.String("type", "rsa", "key `type`: rsa, ecdsa, ed25519, x25519 or secret")
+	bits := fs.Int("bits", 0, "key size in `bits`: 2048 by default for rsa, 256 for secret")
+	curve := fs.String("curve", "P-256", "ecdsa `curve`: P-256, P-384 or P-521")
+	name := fs.String("name", "", "print a shell assignment of the ENCRYPTKEY_ or ENCRYPTSECRET_ variable of key `name`")
+	version := fs.Int("version", 1, "key `version` of the -name variable")
+	out := fs.String("out", "", "write the key to the new `file`, readable by the owner only")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+	if strings.Contains(*name, "_") {
+		return fmt.Errorf("key name %q can not contain \"_\"", *name)
+	}
+	if *version < 1 {
+		return fmt.Errorf("invalid key version %d", *version)
+	}
+
+	key, err := generateKey(*keyType, *bits, *curve)
+	if err != nil {
+		return err
+	}
+	if *name != "" {
+		prefix := config.KEY_ENV_PREFIX
+		if *keyType == "secret" {
+			prefix = config.SECRET_ENV_PREFIX
+		}
+		key = fmt.Sprintf("%s_%s_%d=\"%s\"\n", prefix, *name, *version, strings.TrimSuffix(key, "\n"))
+	}
+	if *out != "" {
+		return writeKeyFile(*out, key)
+	}
+	_, err = io.WriteString(stdout, key)
+	return err
+}
+
+// writeKeyFile writes key to a new file at path that only the owner can
+// read. An existing file is not overwritten as it keeps its permissions.
+func writeKeyFile(path, key string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, key); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// generateKey returns a PKCS#8 PEM private key, or a base64 secret for the
+// secret type.
+func generateKey(keyType string, bits int, curveName string) (string, error) {
+	var privateKey any
+	var err error
+	switch keyType {
+	case "rsa":
+		if bits == 0 {
+			bits = 2048
+		}
+		if bits < 2048 {
+			return "", fmt.Errorf("RSA keys need at least 2048 bits")
+		}
+		privateKey, err = rsa.GenerateKey(rand.Reader, bits)
+	case "ecdsa":
+		curve, ok := curves[curveName]
+		if !ok {
+			return "", fmt.Errorf("unknown curve %q", curveName)
+		}
+		privateKey, err = ecdsa.GenerateKey(curve, rand.Reader)
+	case "ed25519":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	case "x25519":
+		privateKey, err = ecdh.X25519().GenerateKey(rand.Reader)
+	case "secret":
+		if bits == 0 {
+			bits = 256
+		}
+		if bits != 128 && bits != 192 && bits != 256 {
+			return "", fmt.Errorf("secrets have 128, 192 or 256 bits")
+		}
+		secret := make([]byte, bits/8)
+		if _, err := rand.Read(secret); err != nil {
+			return "", err
+		}
+		return base64.B64Encode(secret) + "\n", nil
+	default:
+		return "", fmt.Errorf("unknown key type %q", keyType)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nected/go-lib/crypto"
+)
+
+// keyFlags are the flags of the commands that need keys.
+type keyFlags struct {
+	dir        string
+	file       string
+	secretFile string
+	name       string
+}
+
+func (k *keyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&k.dir, "keys", "", "load every <key_name>_<key_version>.pem file of `dir`")
+	fs.StringVar(&k.file, "key-file", "", "load the PEM key at `path` as version 1 of -key")
+	fs.StringVar(&k.secretFile, "secret-file", "", "load the base64 secret at `path` as version 1 of -key")
+	fs.StringVar(&k.name, "key", "", "key `name`")
+}
+
+// load loads the keys and secrets of the environment and of the flags.
+// Variables of the environment that fail to load are reported on stderr and
+// the keys of the other ones are still used.
+func (k *keyFlags) load(stderr io.Writer) error {
+	if err := crypto.LoadKeysFromEnv(); err != nil {
+		fmt.Fprintf(stderr, "golib-crypto: %v\n", err)
+	}
+	if err := crypto.LoadSecretsFromEnv(); err != nil {
+		fmt.Fprintf(stderr, "golib-crypto: %v\n", err)
+	}
+	if k.dir != "" {
+		if err := crypto.LoadKeysFromDir(k.dir); err != nil {
+			return err
+		}
+	}
+	if (k.file != "" || k.secretFile != "") && k.name == "" {
+		return fmt.Errorf("-key-file and -secret-file need -key")
+	}
+	if k.file != "" {
+		if err := crypto.LoadKeysFromFile(k.name, k.file); err != nil {
+			return err
+		}
+	}
+	if k.secretFile != "" {
+		if err := crypto.LoadSecretFromFile(k.name, k.secretFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readValue returns the value to process: the only argument of fs, the
+// content of the file in, or the standard input. Ciphertexts are trimmed of
+// surrounding white space.
+func readValue(fs *flag.FlagSet, in string, stdin io.Reader, trim bool) ([]byte, error) {
+	var value []byte
+	switch {
+	case fs.NArg() > 1:
+		return nil, fmt.Errorf("too many arguments")
+	case fs.NArg() == 1 && in != "":
+		return nil, fmt.Errorf("both a value and -in given")
+	case fs.NArg() == 1:
+		value = []byte(fs.Arg(0))
+	case in != "":
+		data, err := os.ReadFile(in)
+		if err != nil {
+			return nil, err
+		}
+		value = data
+	default:
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		value = data
+	}
+	if trim {
+		value = []byte(strings.TrimSpace(string(value)))
+	}
+	if len(value) == 0 {
+		return nil, fmt.Errorf("value is empty")
+	}
+	return value, nil
+}
@@ -0,0 +1,83 @@
+// Command golib-crypto generates keys and encrypts, decrypts, inspects and
+// rotates values with the keys of the crypto package.
+//
+// Usage:
+//
+//	golib-crypto <command> [flags] [value]
+//
+// The commands are:
+//
+//	keygen   generate a private key or secret that the key loaders accept
+//	encrypt  encrypt a value
+//	decrypt  decrypt a value
+//	inspect  print the key name, version and type of a value
+//	rotate   re-encrypt a value with the latest version of its key
+//
+// Keys are read like the library reads them: every ENCRYPTKEY_ and
+// ENCRYPTSECRET_ variable of the environment, plus the files given with the
+// -keys, -key-file and -secret-file flags. The value is read from the
+// command line, the -in file or the standard input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"keygen", "generate a private key or secret that the key loaders accept", runKeygen},
+	{"encrypt", "encrypt a value", runEncrypt},
+	{"decrypt", "decrypt a value", runDecrypt},
+	{"inspect", "print the key name, version and type of a value", runInspect},
+	{"rotate", "re-encrypt a value with the latest version of its key", runRotate},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		if err := cmd.run(args[1:], stdin, stdout, stderr); err != nil {
+			if err != flag.ErrHelp {
+				fmt.Fprintf(stderr, "golib-crypto %s: %v\n", cmd.name, err)
+			}
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stderr, "golib-crypto: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: golib-crypto <command> [flags] [value]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(w, "\nRun golib-crypto <command> -h for the flags of a command.\n")
+}
+
+// newFlagSet returns a flag set reporting its errors to stderr.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("golib-crypto "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
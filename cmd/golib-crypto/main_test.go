@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nected/go-lib/crypto"
+)
+
+// runCLI runs the command line args with stdin and returns the exit code and
+// the output.
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestKeygen(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"RSA", []string{"-type", "rsa"}},
+		{"ECDSA", []string{"-type", "ecdsa", "-curve", "P-384"}},
+		{"Ed25519", []string{"-type", "ed25519"}},
+		{"X25519", []string{"-type", "x25519"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyPath := filepath.Join(dir, tt.name+".pem")
+			code, _, stderr := runCLI(t, "", append([]string{"keygen", "-out", keyPath}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("keygen exit code = %v, want 0: %s", code, stderr)
+			}
+			if err := crypto.LoadKeysFromFileToStore(crypto.NewKeyStore(), "KEYGEN", keyPath); err != nil {
+				t.Errorf("LoadKeysFromFileToStore() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestKeygenExistingFile(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "KEYGEN_1.pem")
+	if err := os.WriteFile(keyPath, []byte("existing"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if code, _, _ := runCLI(t, "", "keygen", "-out", keyPath); code != 1 {
+		t.Errorf("keygen exit code = %v, want 1", code)
+	}
+	if data, _ := os.ReadFile(keyPath); string(data) != "existing" {
+		t.Errorf("keygen overwrote %s", keyPath)
+	}
+}
+
+func TestKeygenErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"Unknown type", []string{"-type", "dsa"}},
+		{"Short RSA key", []string{"-type", "rsa", "-bits", "1024"}},
+		{"Unknown curve", []string{"-type", "ecdsa", "-curve", "P-224"}},
+		{"Secret size", []string{"-type", "secret", "-bits", "64"}},
+		{"Name with separator", []string{"-name", "MY_KEY"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, _ := runCLI(t, "", append([]string{"keygen"}, tt.args...)...); code != 1 {
+				t.Errorf("keygen exit code = %v, want 1", code)
+			}
+		})
+	}
+}
+
+func TestEncryptDecryptRSA(t *testing.T) {
+	dir := t.TempDir()
+	if code, _, stderr := runCLI(t, "", "keygen", "-out", filepath.Join(dir, "CLIKEY_1.pem")); code != 0 {
+		t.Fatalf("keygen exit code = %v, want 0: %s", code, stderr)
+	}
+
+	code, ciphertext, stderr := runCLI(t, "", "encrypt", "-keys", dir, "-key", "CLIKEY", "hello world")
+	if code != 0 {
+		t.Fatalf("encrypt exit code = %v, want 0: %s", code, stderr)
+	}
+
+	code, inspected, stderr := runCLI(t, ciphertext, "inspect", "-keys", dir)
+	if code != 0 {
+		t.Fatalf("inspect exit code = %v, want 0: %s", code, stderr)
+	}
+	want := "name:    CLIKEY\nversion: 1\ntype:    RSA\nkey:     ACTIVE\n"
+	if inspected != want {
+		t.Errorf("inspect = %q, want %q", inspected, want)
+	}
+
+	code, plaintext, stderr := runCLI(t, ciphertext, "decrypt", "-keys", dir)
+	if code != 0 {
+		t.Fatalf("decrypt exit code = %v, want 0: %s", code, stderr)
+	}
+	if plaintext != "hello world" {
+		t.Errorf("decrypt = %v, want %v", plaintext, "hello world")
+	}
+
+	// rotate to a second version of the key
+	if code, _, stderr := runCLI(t, "", "keygen", "-out", filepath.Join(dir, "CLIKEY_2.pem")); code != 0 {
+		t.Fatalf("keygen exit code = %v, want 0: %s", code, stderr)
+	}
+	code, rotated, stderr := runCLI(t, ciphertext, "rotate", "-keys", dir)
+	if code != 0 {
+		t.Fatalf("rotate exit code = %v, want 0: %s", code, stderr)
+	}
+	code, inspected, _ = runCLI(t, rotated, "inspect", "-keys", dir)
+	if code != 0 || !strings.Contains(inspected, "version: 2\n") {
+		t.Errorf("inspect = %q, want version 2", inspected)
+	}
+	code, plaintext, _ = runCLI(t, rotated, "decrypt", "-keys", dir)
+	if code != 0 || plaintext != "hello world" {
+		t.Errorf("decrypt = %v, want %v", plaintext, "hello world")
+	}
+}
+
+func TestEncryptDecryptSecretFromEnv(t *testing.T) {
+	code, assignment, stderr := runCLI(t, "", "keygen", "-type", "secret", "-name", "CLISECRET", "-version", "3")
+	if code != 0 {
+		t.Fatalf("keygen exit code = %v, want 0: %s", code, stderr)
+	}
+	name, value, _ := strings.Cut(strings.TrimSpace(assignment), "=")
+	if name != "ENCRYPTSECRET_CLISECRET_3" {
+		t.Fatalf("keygen variable = %v, want %v", name, "ENCRYPTSECRET_CLISECRET_3")
+	}
+	t.Setenv(name, strings.Trim(value, `"`))
+
+	for _, mode := range []string{"aes", "deterministic"} {
+		t.Run(mode, func(t *testing.T) {
+			in := filepath.Join(t.TempDir(), "value")
+			if err := os.WriteFile(in, []byte("4111 1111 1111 1111"), 0o600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			code, ciphertext, stderr := runCLI(t, "", "encrypt", "-mode", mode, "-key", "CLISECRET", "-in", in)
+			if code != 0 {
+				t.Fatalf("encrypt exit code = %v, want 0: %s", code, stderr)
+			}
+			code, plaintext, stderr := runCLI(t, ciphertext, "decrypt")
+			if code != 0 {
+				t.Fatalf("decrypt exit code = %v, want 0: %s", code, stderr)
+			}
+			if plaintext != "4111 1111 1111 1111" {
+				t.Errorf("decrypt = %v, want %v", plaintext, "4111 1111 1111 1111")
+			}
+		})
+	}
+}
+
+func TestInvalidEnvKey(t *testing.T) {
+	dir := t.TempDir()
+	if code, _, stderr := runCLI(t, "", "keygen", "-out", filepath.Join(dir, "CLIKEY_1.pem")); code != 0 {
+		t.Fatalf("keygen exit code = %v, want 0: %s", code, stderr)
+	}
+	t.Setenv("ENCRYPTKEY_BROKENKEY_1", "not a key")
+
+	code, ciphertext, stderr := runCLI(t, "", "encrypt", "-keys", dir, "-key", "CLIKEY", "hello world")
+	if code != 0 {
+		t.Fatalf("encrypt exit code = %v, want 0: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, "ENCRYPTKEY_BROKENKEY_1") {
+		t.Errorf("encrypt stderr = %q, want the invalid variable reported", stderr)
+	}
+	code, plaintext, stderr := runCLI(t, ciphertext, "decrypt", "-keys", dir)
+	if code != 0 || plaintext != "hello world" {
+		t.Errorf("decrypt = %v, want %v: %s", plaintext, "hello world", stderr)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  int
+	}{
+		{"No command", "", nil, 2},
+		{"Unknown command", "", []string{"sign"}, 2},
+		{"Encrypt without key", "", []string{"encrypt", "hello"}, 1},
+		{"Encrypt unknown mode", "", []string{"encrypt", "-key", "CLIKEY", "-mode", "rot13", "hello"}, 1},
+		{"Encrypt unknown key", "", []string{"encrypt", "-key", "MISSING", "hello"}, 1},
+		{"Key file without key", "", []string{"decrypt", "-key-file", "key.pem", "hello"}, 1},
+		{"Decrypt plain text", "hello", []string{"decrypt"}, 1},
+		{"Decrypt empty", "", []string{"decrypt"}, 1},
+		{"Inspect plain text", "", []string{"inspect", "hello"}, 1},
+		{"Rotate plain text", "", []string{"rotate", "hello"}, 1},
+		{"Too many arguments", "", []string{"inspect", "a", "b"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, _ := runCLI(t, tt.stdin, tt.args...); code != tt.want {
+				t.Errorf("exit code = %v, want %v", code, tt.want)
+			}
+		})
+	}
+}
@@ -4,48 +4,60 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"fmt"
 
 	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
 	"github.com/nected/go-lib/crypto/models"
 )
 
+// nonceSize is the standard AES-GCM nonce size.
+const nonceSize = 12
+
 func Encrypt(secret string, data []byte) (*models.Payload, error) {
+	return EncryptWithAAD(secret, data, nil)
+}
+
+// EncryptWithAAD behaves like Encrypt but binds the ciphertext to
+// additionalData, e.g. a tenant or record ID, so it can only be decrypted
+// with DecryptWithAAD and the same additionalData.
+func EncryptWithAAD(secret string, data, additionalData []byte) (*models.Payload, error) {
 	if len(data) == 0 {
-		return nil, nil
-	}
-	block, err := aes.NewCipher([]byte(secret))
-	if err != nil {
-		return nil, err
+		return nil, errors.ErrEmptyData
 	}
 
-	gcm, err := cipher.NewGCM(block)
-
+	// encrypt data
+	encryptedData, err := Seal([]byte(secret), data, additionalData)
 	if err != nil {
 		return nil, err
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	// fill nonce with random data
-	if _, err = rand.Read(nonce); err != nil {
-		return nil, err
-	}
-
-	// encrypt data
-	encryptedData := gcm.Seal(nonce, nonce, data, nil)
-
 	encryptedDataString := base64.B64EncodeURL(encryptedData)
 
 	return &models.Payload{
-		KeyType:       models.KeyTypeAES,
-		Data:          string(data),
-		EncryptedData: encryptedDataString,
+		KeyType:        models.KeyTypeAES,
+		Data:           string(data),
+		EncryptedData:  encryptedDataString,
+		AssociatedData: string(additionalData),
 	}, nil
 }
 
 func Decrypt(secret string, data string) (*models.Payload, error) {
+	return DecryptWithAAD(secret, data, nil)
+}
+
+// DecryptWithAAD decrypts data produced by EncryptWithAAD. It returns
+// errors.ErrAuthenticationFailed when additionalData does not match the one
+// used to encrypt.
+func DecryptWithAAD(secret string, data string, additionalData []byte) (*models.Payload, error) {
 	p := models.Payload{}
 	if data == "" {
-		return nil, nil
+		return nil, errors.ErrEmptyData
+	}
+
+	// passphrase ciphertexts carry their own KDF header
+	if isPassphraseData(data) {
+		return decryptWithPassphrase(secret, data, additionalData)
 	}
 
 	// decode data
@@ -54,29 +66,60 @@ func Decrypt(secret string, data string) (*models.Payload, error) {
 		return nil, err
 	}
 
-	block, err := aes.NewCipher([]byte(secret))
+	decryptedData, err := Open([]byte(secret), []byte(decodedData), additionalData)
 	if err != nil {
 		return nil, err
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	p.Data = string(decryptedData)
+	p.AssociatedData = string(additionalData)
+	return &p, nil
+}
 
+// Seal encrypts data with AES-GCM under key, which must be 16, 24 or 32 bytes
+// long, and returns nonce||ciphertext. additionalData is authenticated but not
+// encrypted and may be nil.
+func Seal(key, data, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
 	if err != nil {
 		return nil, err
 	}
 
-	nonceSize := gcm.NonceSize()
-	if len(decodedData) < nonceSize {
+	nonce := make([]byte, gcm.NonceSize())
+	// fill nonce with random data
+	if _, err = rand.Read(nonce); err != nil {
 		return nil, err
 	}
 
-	nonce, encryptedData := decodedData[:nonceSize], decodedData[nonceSize:]
+	return gcm.Seal(nonce, nonce, data, additionalData), nil
+}
 
-	decryptedData, err := gcm.Open(nil, []byte(nonce), []byte(encryptedData), nil)
+// Open decrypts nonce||ciphertext produced by Seal with the same key and
+// additionalData, returning errors.ErrAuthenticationFailed on any mismatch.
+func Open(key, data, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
 	if err != nil {
 		return nil, err
 	}
 
-	p.Data = string(decryptedData)
-	return &p, nil
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize+gcm.Overhead() {
+		return nil, errors.ErrTruncatedData
+	}
+
+	nonce, encryptedData := data[:nonceSize], data[nonceSize:]
+
+	decryptedData, err := gcm.Open(nil, nonce, encryptedData, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrAuthenticationFailed, err)
+	}
+	return decryptedData, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
@@ -1,9 +1,11 @@
 package aes
 
 import (
+	stdErrors "errors"
 	"reflect"
 	"testing"
 
+	"github.com/nected/go-lib/crypto/errors"
 	"github.com/nected/go-lib/crypto/models"
 )
 
@@ -36,7 +38,7 @@ func TestDecryptAES(t *testing.T) {
 				data:   "",
 			},
 			want:    nil,
-			wantErr: false,
+			wantErr: true,
 		},
 		{
 			name: "Invalid base64 data",
@@ -112,7 +114,7 @@ func TestEncryptAES(t *testing.T) {
 				data:   []byte(""),
 			},
 			want:    nil,
-			wantErr: false,
+			wantErr: true,
 		},
 		{
 			name: "Invalid secret",
@@ -153,3 +155,48 @@ func TestEncryptAES(t *testing.T) {
 		})
 	}
 }
+
+func TestEncryptWithAAD(t *testing.T) {
+	secret := "someRandomSecret"
+	got, err := EncryptWithAAD(secret, []byte("data"), []byte("tenant-1"))
+	if err != nil {
+		t.Fatalf("EncryptWithAAD() error = %v", err)
+	}
+	if got.AssociatedData != "tenant-1" {
+		t.Errorf("EncryptWithAAD() AssociatedData = %v, want %v", got.AssociatedData, "tenant-1")
+	}
+	passphraseData, err := EncryptWithPassphraseParams("passphrase", []byte("data"), testKDFParams)
+	if err != nil {
+		t.Fatalf("EncryptWithPassphraseParams() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		data    string
+		aad     []byte
+		wantErr error
+	}{
+		{name: "Matching context", secret: secret, data: got.EncryptedData, aad: []byte("tenant-1")},
+		{name: "Other context", secret: secret, data: got.EncryptedData, aad: []byte("tenant-2"), wantErr: errors.ErrAuthenticationFailed},
+		{name: "Missing context", secret: secret, data: got.EncryptedData, aad: nil, wantErr: errors.ErrAuthenticationFailed},
+		{name: "Unexpected context", secret: "passphrase", data: passphraseData.EncryptedData, aad: []byte("tenant-1"), wantErr: errors.ErrAuthenticationFailed},
+		{name: "Truncated nonce", secret: secret, data: "AAAA", wantErr: errors.ErrTruncatedData},
+		{name: "Truncated tag", secret: secret, data: got.EncryptedData[:28], wantErr: errors.ErrTruncatedData},
+		{name: "Invalid base64 data", secret: secret, data: "invalid_base64", wantErr: errors.ErrInvalidData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := DecryptWithAAD(tt.secret, tt.data, tt.aad)
+			if tt.wantErr != nil {
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Errorf("DecryptWithAAD() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || payload.Data != "data" {
+				t.Errorf("DecryptWithAAD() = %v, %v, want data", payload, err)
+			}
+		})
+	}
+}
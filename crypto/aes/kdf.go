@@ -0,0 +1,153 @@
+package aes
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+	"golang.org/x/crypto/argon2"
+)
+
+// Passphrase ciphertext format
+//
+//	$argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<nonce||ciphertext>
+//
+// salt and nonce||ciphertext are URL-safe base64 encoded. The header is never
+// valid base64, so Decrypt can tell it apart from raw-key ciphertexts.
+const (
+	kdfPrefix     = "$argon2id$"
+	kdfKeySize    = 32
+	kdfSaltLength = 16
+)
+
+// KDFParams are the Argon2id parameters used to derive an AES-256 key from a
+// passphrase.
+type KDFParams struct {
+	// Memory in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// DefaultKDFParams follow the second recommended option of RFC 9106.
+var DefaultKDFParams = KDFParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+}
+
+// MaxKDFParams bound the KDF parameters accepted on encrypt and decrypt. The
+// parameters of a passphrase ciphertext come from its untrusted header, so
+// without a bound a single forged ciphertext could make Decrypt allocate
+// gigabytes or spin for minutes. The default allows 256 MiB, 4 iterations and
+// 4 lanes; raise it before decrypting ciphertexts made with stronger
+// parameters.
+var MaxKDFParams = KDFParams{
+	Memory:      256 * 1024,
+	Iterations:  4,
+	Parallelism: 4,
+}
+
+// EncryptWithPassphrase encrypts data with an AES-256 key derived from
+// passphrase with Argon2id and DefaultKDFParams. Unlike Encrypt, passphrase
+// may have any length. The salt and KDF parameters are embedded in the
+// ciphertext, which Decrypt detects automatically.
+//
+// Parameters:
+//   - passphrase: A human chosen secret of any length.
+//   - data: The data to be encrypted.
+//
+// Returns:
+//   - *models.Payload: A payload containing the original data and the encrypted data.
+//   - error: An error if the encryption process fails.
+func EncryptWithPassphrase(passphrase string, data []byte) (*models.Payload, error) {
+	return EncryptWithPassphraseParams(passphrase, data, DefaultKDFParams)
+}
+
+// EncryptWithPassphraseParams behaves like EncryptWithPassphrase with custom
+// KDF parameters.
+func EncryptWithPassphraseParams(passphrase string, data []byte, params KDFParams) (*models.Payload, error) {
+	if len(data) == 0 {
+		return nil, errors.ErrEmptyData
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("%w: passphrase is empty", errors.ErrInvalidData)
+	}
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, kdfSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	encryptedData, err := Seal(params.deriveKey(passphrase, salt), data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Payload{
+		KeyType: models.KeyTypeAES,
+		Data:    string(data),
+		EncryptedData: fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", kdfPrefix, argon2.Version,
+			params.Memory, params.Iterations, params.Parallelism,
+			base64.B64EncodeURL(salt), base64.B64EncodeURL(encryptedData)),
+	}, nil
+}
+
+// isPassphraseData reports whether data was produced by EncryptWithPassphrase.
+func isPassphraseData(data string) bool {
+	return strings.HasPrefix(data, kdfPrefix)
+}
+
+// decryptWithPassphrase reverses EncryptWithPassphraseParams.
+func decryptWithPassphrase(passphrase string, data string, additionalData []byte) (*models.Payload, error) {
+	parts := strings.Split(strings.TrimPrefix(data, kdfPrefix), "$")
+	if len(parts) != 4 {
+		return nil, errors.ErrInvalidData
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errors.ErrInvalidData
+	}
+	var params KDFParams
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, errors.ErrInvalidData
+	}
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	salt, err := base64.B64DecodeURL(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	encryptedData, err := base64.B64DecodeURL(parts[3])
+	if err != nil {
+		return nil, err
+	}
+
+	decryptedData, err := Open(params.deriveKey(passphrase, []byte(salt)), []byte(encryptedData), additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Payload{Data: string(decryptedData), AssociatedData: string(additionalData)}, nil
+}
+
+func (p KDFParams) validate() error {
+	if p.Memory < 8*uint32(p.Parallelism) || p.Memory > MaxKDFParams.Memory ||
+		p.Iterations < 1 || p.Iterations > MaxKDFParams.Iterations ||
+		p.Parallelism < 1 || p.Parallelism > MaxKDFParams.Parallelism {
+		return fmt.Errorf("%w: unsupported KDF parameters m=%d,t=%d,p=%d", errors.ErrInvalidData, p.Memory, p.Iterations, p.Parallelism)
+	}
+	return nil
+}
+
+func (p KDFParams) deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, p.Iterations, p.Memory, p.Parallelism, kdfKeySize)
+}
@@ -0,0 +1,110 @@
+package aes
+
+import (
+	"strings"
+	"testing"
+)
+
+// testKDFParams keep the tests fast, DefaultKDFParams is covered separately.
+var testKDFParams = KDFParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	got, err := EncryptWithPassphrase("correct horse battery staple", []byte("data"))
+	if err != nil {
+		t.Fatalf("EncryptWithPassphrase() error = %v", err)
+	}
+	if !strings.HasPrefix(got.EncryptedData, "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Errorf("EncryptWithPassphrase() = %v, want argon2id header with default params", got.EncryptedData)
+	}
+	payload, err := Decrypt("correct horse battery staple", got.EncryptedData)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if payload.Data != "data" {
+		t.Errorf("Decrypt() = %v, want %v", payload.Data, "data")
+	}
+}
+
+func TestDecryptWithPassphrase(t *testing.T) {
+	got, err := EncryptWithPassphraseParams("passphrase", []byte("data"), testKDFParams)
+	if err != nil {
+		t.Fatalf("EncryptWithPassphraseParams() error = %v", err)
+	}
+	encrypted := got.EncryptedData
+	parts := strings.Split(encrypted, "$")
+
+	tests := []struct {
+		name       string
+		passphrase string
+		data       string
+		want       string
+		wantErr    bool
+	}{
+		{name: "Valid decryption", passphrase: "passphrase", data: encrypted, want: "data"},
+		{name: "Wrong passphrase", passphrase: "Passphrase", data: encrypted, wantErr: true},
+		{name: "Modified params", passphrase: "passphrase", data: strings.Replace(encrypted, "t=1", "t=2", 1), wantErr: true},
+		{name: "Excessive memory", passphrase: "passphrase", data: strings.Replace(encrypted, "m=1024", "m=4194304", 1), wantErr: true},
+		{name: "Memory above MaxKDFParams", passphrase: "passphrase", data: strings.Replace(encrypted, "m=1024", "m=524288", 1), wantErr: true},
+		{name: "Iterations above MaxKDFParams", passphrase: "passphrase", data: strings.Replace(encrypted, "t=1", "t=5", 1), wantErr: true},
+		{name: "Parallelism above MaxKDFParams", passphrase: "passphrase", data: strings.Replace(encrypted, "p=1", "p=8", 1), wantErr: true},
+		{name: "Unknown version", passphrase: "passphrase", data: strings.Replace(encrypted, "v=19", "v=16", 1), wantErr: true},
+		{name: "Missing salt", passphrase: "passphrase", data: strings.Join(append(parts[:4], parts[5]), "$"), wantErr: true},
+		{name: "Invalid ciphertext", passphrase: "passphrase", data: strings.Join(append(parts[:5], "invalid_base64"), "$"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decrypt(tt.passphrase, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got.Data != tt.want {
+				t.Errorf("Decrypt() = %v, want %v", got.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptWithPassphraseParams(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		data       []byte
+		params     KDFParams
+		wantErr    bool
+	}{
+		{name: "Short passphrase", passphrase: "1", data: []byte("data"), params: testKDFParams},
+		{name: "Empty data", passphrase: "passphrase", data: []byte(""), params: testKDFParams, wantErr: true},
+		{name: "Empty passphrase", passphrase: "", data: []byte("data"), params: testKDFParams, wantErr: true},
+		{name: "Iterations above MaxKDFParams", passphrase: "passphrase", data: []byte("data"), params: KDFParams{Memory: 1024, Iterations: 5, Parallelism: 1}, wantErr: true},
+		{name: "Zero iterations", passphrase: "passphrase", data: []byte("data"), params: KDFParams{Memory: 1024, Parallelism: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncryptWithPassphraseParams(tt.passphrase, tt.data, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EncryptWithPassphraseParams() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if got != nil {
+					t.Errorf("EncryptWithPassphraseParams() = %v, want nil", got)
+				}
+				return
+			}
+			payload, err := Decrypt(tt.passphrase, got.EncryptedData)
+			if err != nil || payload.Data != string(tt.data) {
+				t.Errorf("Decrypt() = %v, %v, want %s", payload, err, tt.data)
+			}
+		})
+	}
+
+	// raw-key ciphertexts keep decrypting next to passphrase ones
+	raw, err := Encrypt("someRandomSecret", []byte("data"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if payload, err := Decrypt("someRandomSecret", raw.EncryptedData); err != nil || payload.Data != "data" {
+		t.Errorf("Decrypt() = %v, %v, want data", payload, err)
+	}
+}
@@ -0,0 +1,144 @@
+package aes
+
+import (
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// Named secrets are versioned AES keys held by models.DefaultSecretStore. Their
+// payloads serialize like RSA ones, with the "aes" wire tag:
+//
+//	base64("$keyName$keyVersion$aes:" + base64(nonce||ciphertext))
+//
+// so the secret that produced a value can be identified and rotated. The
+// ciphertext is bound to the key name and version.
+
+// EncryptNamed encrypts data with the latest active version of the named
+// secret. Serialize the result with Payload.String.
+//
+// Parameters:
+//   - keyName: The name of the secret to use.
+//   - data: The data to be encrypted.
+//
+// Returns:
+//   - *models.Payload: A payload of type models.KeyTypeAES carrying the key
+//     name and version.
+//   - error: errors.ErrKeyNotFound if the secret is not loaded, or an error
+//     if the encryption process fails.
+func EncryptNamed(keyName string, data []byte) (*models.Payload, error) {
+	return EncryptNamedWithStore(models.DefaultSecretStore(), keyName, data)
+}
+
+// EncryptNamedWithStore behaves like EncryptNamed but looks the secret up in
+// store instead of the default secret store.
+func EncryptNamedWithStore(store models.KeyStore, keyName string, data []byte) (*models.Payload, error) {
+	if len(data) == 0 {
+		return nil, errors.ErrEmptyData
+	}
+	keyInfo, err := models.FindKey(store, keyName, 0)
+	if err != nil {
+		return nil, err
+	}
+	return encryptWithKey(keyInfo, data)
+}
+
+func encryptWithKey(keyInfo *models.KeyInfo, data []byte) (*models.Payload, error) {
+	encryptedData, err := Seal(keyInfo.GetSecret(), data, keyInfo.Label(nil))
+	if err != nil {
+		return nil, err
+	}
+	return &models.Payload{
+		KeyName:       keyInfo.GetName(),
+		KeyVersion:    keyInfo.GetVersion(),
+		KeyType:       models.KeyTypeAES,
+		Data:          string(data),
+		EncryptedData: base64.B64Encode(encryptedData),
+	}, nil
+}
+
+// DecryptNamed decrypts a serialized payload produced by EncryptNamed or
+// EncryptDeterministic. The
+// secret version is read from the payload. Data that is not a named AES
+// payload is returned as is, like rsa.Decrypt does for plain text.
+//
+// Returns errors.ErrKeyNotFound if the secret version is not loaded and
+// errors.ErrKeyRevoked if it has been revoked.
+func DecryptNamed(data string) (*models.Payload, error) {
+	return DecryptNamedWithStore(models.DefaultSecretStore(), data)
+}
+
+// DecryptNamedWithStore behaves like DecryptNamed but looks the secret up in
+// store instead of the default secret store.
+func DecryptNamedWithStore(store models.KeyStore, data string) (*models.Payload, error) {
+	if data == "" {
+		return nil, errors.ErrEmptyData
+	}
+	parsed := models.ParsePayload(data)
+	if parsed == nil || !IsNamedKeyType(parsed.KeyType) {
+		return &models.Payload{Data: data}, nil
+	}
+
+	keyInfo, err := models.FindDecryptionKey(store, parsed.KeyName, parsed.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedData, err := base64.B64Decode(parsed.EncryptedData)
+	if err != nil {
+		return nil, err
+	}
+	var decryptedData []byte
+	if parsed.KeyType == models.KeyTypeAESSIV {
+		decryptedData, err = decryptDeterministicWithKey(keyInfo, []byte(encryptedData))
+	} else {
+		decryptedData, err = Open(keyInfo.GetSecret(), []byte(encryptedData), keyInfo.Label(nil))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parsed.Data = string(decryptedData)
+	return parsed, nil
+}
+
+// Rotate re-encrypts a named AES payload with the latest active version of
+// its secret. It mirrors rsa.Rotate: values that are not named AES payloads,
+// or that already use the latest version, are returned unchanged.
+func Rotate(ciphertext string) (string, bool, error) {
+	return RotateWithStore(models.DefaultSecretStore(), ciphertext)
+}
+
+// RotateWithStore behaves like Rotate but looks secrets up in store instead
+// of the default secret store.
+func RotateWithStore(store models.KeyStore, ciphertext string) (string, bool, error) {
+	parsed := models.ParsePayload(ciphertext)
+	if parsed == nil || !IsNamedKeyType(parsed.KeyType) {
+		return ciphertext, false, nil
+	}
+	latest := models.LookupKey(store, parsed.KeyName, 0)
+	if latest == nil || latest.GetVersion() <= parsed.KeyVersion {
+		return ciphertext, false, nil
+	}
+
+	payload, err := DecryptNamedWithStore(store, ciphertext)
+	if err != nil {
+		return ciphertext, false, err
+	}
+	// keep the format the value was originally encrypted with
+	encryptFn := encryptWithKey
+	if payload.KeyType == models.KeyTypeAESSIV {
+		encryptFn = encryptDeterministicWithKey
+	}
+	rotated, err := encryptFn(latest, []byte(payload.Data))
+	if err != nil {
+		return ciphertext, false, err
+	}
+	return rotated.String(), true, nil
+}
+
+// IsNamedKeyType reports whether keyType is produced by EncryptNamed or
+// EncryptDeterministic.
+func IsNamedKeyType(keyType models.KeyType) bool {
+	return keyType == models.KeyTypeAES || keyType == models.KeyTypeAESSIV
+}
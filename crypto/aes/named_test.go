@@ -0,0 +1,150 @@
+package aes
+
+import (
+	stdErrors "errors"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func newTestSecretStore(t *testing.T, versions ...models.KeyState) models.KeyStore {
+	t.Helper()
+	store := models.NewMemoryKeyStore()
+	for i, state := range versions {
+		secret := make([]byte, 32)
+		secret[0] = byte(i + 1)
+		err := store.Put(models.KeyInfo{Name: "SECRET", Version: i + 1, State: state, Secret: secret})
+		if err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	return store
+}
+
+func TestEncryptNamed(t *testing.T) {
+	store := newTestSecretStore(t, models.KeyStateActive, models.KeyStateActive)
+
+	payload, err := EncryptNamedWithStore(store, "SECRET", []byte("data"))
+	if err != nil {
+		t.Fatalf("EncryptNamedWithStore() error = %v", err)
+	}
+	if payload.KeyVersion != 2 || payload.KeyType != models.KeyTypeAES {
+		t.Errorf("EncryptNamedWithStore() = %v, want version 2 of type %v", payload, models.KeyTypeAES)
+	}
+
+	parsed := models.ParsePayload(payload.String())
+	if parsed == nil || parsed.KeyName != "SECRET" || parsed.KeyVersion != 2 || parsed.KeyType != models.KeyTypeAES {
+		t.Fatalf("ParsePayload() = %v, want the SECRET version 2 header", parsed)
+	}
+
+	got, err := DecryptNamedWithStore(store, payload.String())
+	if err != nil {
+		t.Fatalf("DecryptNamedWithStore() error = %v", err)
+	}
+	if got.Data != "data" || got.KeyVersion != 2 {
+		t.Errorf("DecryptNamedWithStore() = %v, want data of version 2", got)
+	}
+
+	if _, err := EncryptNamedWithStore(store, "MISSING", []byte("data")); !stdErrors.Is(err, errors.ErrKeyNotFound) {
+		t.Errorf("EncryptNamedWithStore() error = %v, want %v", err, errors.ErrKeyNotFound)
+	}
+	if _, err := EncryptNamedWithStore(store, "SECRET", nil); !stdErrors.Is(err, errors.ErrEmptyData) {
+		t.Errorf("EncryptNamedWithStore() error = %v, want %v", err, errors.ErrEmptyData)
+	}
+}
+
+func TestDecryptNamed(t *testing.T) {
+	store := newTestSecretStore(t, models.KeyStateActive)
+	payload, err := EncryptNamedWithStore(store, "SECRET", []byte("data"))
+	if err != nil {
+		t.Fatalf("EncryptNamedWithStore() error = %v", err)
+	}
+
+	// a ciphertext moved to another key version must not decrypt
+	forged := *payload
+	forged.KeyVersion = 2
+	secret := make([]byte, 32)
+	secret[0] = 1
+	if err := store.Put(models.KeyInfo{Name: "SECRET", Version: 2, Secret: secret}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "Named payload",
+			data: payload.String(),
+			want: "data",
+		},
+		{
+			name: "Plain text",
+			data: "hello world",
+			want: "hello world",
+		},
+		{
+			name:    "Empty data",
+			data:    "",
+			wantErr: errors.ErrEmptyData,
+		},
+		{
+			name:    "Unknown version",
+			data:    (&models.Payload{KeyName: "SECRET", KeyVersion: 9, KeyType: models.KeyTypeAES, EncryptedData: payload.EncryptedData}).String(),
+			wantErr: errors.ErrKeyNotFound,
+		},
+		{
+			name:    "Version swapped",
+			data:    forged.String(),
+			wantErr: errors.ErrAuthenticationFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptNamedWithStore(store, tt.data)
+			if !stdErrors.Is(err, tt.wantErr) {
+				t.Fatalf("DecryptNamedWithStore() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Data != tt.want {
+				t.Errorf("DecryptNamedWithStore() = %v, want %v", got.Data, tt.want)
+			}
+		})
+	}
+
+	if err := models.SetKeyState(store, "SECRET", 1, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if _, err := DecryptNamedWithStore(store, payload.String()); !stdErrors.Is(err, errors.ErrKeyRevoked) {
+		t.Errorf("DecryptNamedWithStore() error = %v, want %v", err, errors.ErrKeyRevoked)
+	}
+}
+
+func TestRotateNamed(t *testing.T) {
+	store := newTestSecretStore(t, models.KeyStateActive)
+	payload, err := EncryptNamedWithStore(store, "SECRET", []byte("data"))
+	if err != nil {
+		t.Fatalf("EncryptNamedWithStore() error = %v", err)
+	}
+
+	if _, rotated, err := RotateWithStore(store, payload.String()); err != nil || rotated {
+		t.Fatalf("RotateWithStore() = %v, %v, want no rotation", rotated, err)
+	}
+
+	if err := store.Put(models.KeyInfo{Name: "SECRET", Version: 2, Secret: make([]byte, 16)}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	ciphertext, rotated, err := RotateWithStore(store, payload.String())
+	if err != nil || !rotated {
+		t.Fatalf("RotateWithStore() = %v, %v, want rotation", rotated, err)
+	}
+	got, err := DecryptNamedWithStore(store, ciphertext)
+	if err != nil {
+		t.Fatalf("DecryptNamedWithStore() error = %v", err)
+	}
+	if got.Data != "data" || got.KeyVersion != 2 {
+		t.Errorf("DecryptNamedWithStore() = %v, want data of version 2", got)
+	}
+}
@@ -0,0 +1,243 @@
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Deterministic payloads use AES-SIV (RFC 5297) and the "siv" wire tag:
+//
+//	base64("$keyName$keyVersion$siv:" + base64(siv||ciphertext))
+//
+// AES-SIV derives its IV from the key, the associated data and the plaintext,
+// so the same plaintext encrypted with the same secret version always gives
+// the same ciphertext. That makes the ciphertext usable as a join or lookup
+// key, but it also LEAKS EQUALITY: anyone who can read the ciphertexts learns
+// which records hold equal values, and how often each value occurs. Use it
+// only for fields that must be compared, and EncryptNamed everywhere else.
+
+// sivSize is the size of the synthetic IV prepended to the ciphertext.
+const sivSize = aes.BlockSize
+
+// sivHKDFInfo separates AES-SIV keys from any other key derived from a
+// secret.
+const sivHKDFInfo = "go-lib aes-siv"
+
+// EncryptDeterministic encrypts data with AES-SIV under the latest active
+// version of the named secret. Read the equality warning above before using
+// it.
+//
+// Parameters:
+//   - keyName: The name of the secret to use.
+//   - data: The data to be encrypted.
+//
+// Returns:
+//   - *models.Payload: A payload of type models.KeyTypeAESSIV, decrypted by
+//     DecryptNamed.
+//   - error: errors.ErrKeyNotFound if the secret is not loaded, or an error
+//     if the encryption process fails.
+func EncryptDeterministic(keyName string, data []byte) (*models.Payload, error) {
+	return EncryptDeterministicWithStore(models.DefaultSecretStore(), keyName, data)
+}
+
+// EncryptDeterministicWithStore behaves like EncryptDeterministic but looks
+// the secret up in store instead of the default secret store.
+func EncryptDeterministicWithStore(store models.KeyStore, keyName string, data []byte) (*models.Payload, error) {
+	if len(data) == 0 {
+		return nil, errors.ErrEmptyData
+	}
+	keyInfo, err := models.FindKey(store, keyName, 0)
+	if err != nil {
+		return nil, err
+	}
+	return encryptDeterministicWithKey(keyInfo, data)
+}
+
+func encryptDeterministicWithKey(keyInfo *models.KeyInfo, data []byte) (*models.Payload, error) {
+	key, err := sivKey(keyInfo.GetSecret())
+	if err != nil {
+		return nil, err
+	}
+	encryptedData, err := SealSIV(key, data, keyInfo.Label(nil))
+	if err != nil {
+		return nil, err
+	}
+	return &models.Payload{
+		KeyName:       keyInfo.GetName(),
+		KeyVersion:    keyInfo.GetVersion(),
+		KeyType:       models.KeyTypeAESSIV,
+		Data:          string(data),
+		EncryptedData: base64.B64Encode(encryptedData),
+	}, nil
+}
+
+func decryptDeterministicWithKey(keyInfo *models.KeyInfo, encryptedData []byte) ([]byte, error) {
+	key, err := sivKey(keyInfo.GetSecret())
+	if err != nil {
+		return nil, err
+	}
+	return OpenSIV(key, encryptedData, keyInfo.Label(nil))
+}
+
+// sivKey derives the double length AES-SIV key from a 16, 24 or 32 byte
+// secret.
+func sivKey(secret []byte) ([]byte, error) {
+	switch len(secret) {
+	case 16, 24, 32:
+	default:
+		return nil, aes.KeySizeError(len(secret))
+	}
+	key := make([]byte, 2*len(secret))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(sivHKDFInfo)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// SealSIV encrypts data with AES-SIV as specified by RFC 5297 and returns
+// siv||ciphertext. key must be 32, 48 or 64 bytes long: its first half keys
+// the S2V MAC and its second half AES-CTR. Each additionalData element is
+// authenticated as a separate associated data component, e.g. a header and
+// a nonce.
+func SealSIV(key, data []byte, additionalData ...[]byte) ([]byte, error) {
+	macBlock, ctrBlock, err := newSIVBlocks(key)
+	if err != nil {
+		return nil, err
+	}
+	v := s2v(macBlock, additionalData, data)
+	out := make([]byte, sivSize+len(data))
+	copy(out, v)
+	sivCTR(ctrBlock, v).XORKeyStream(out[sivSize:], data)
+	return out, nil
+}
+
+// OpenSIV decrypts siv||ciphertext produced by SealSIV with the same key and
+// additionalData, returning errors.ErrAuthenticationFailed on any mismatch.
+func OpenSIV(key, data []byte, additionalData ...[]byte) ([]byte, error) {
+	macBlock, ctrBlock, err := newSIVBlocks(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < sivSize {
+		return nil, errors.ErrTruncatedData
+	}
+	v, encryptedData := data[:sivSize], data[sivSize:]
+	decryptedData := make([]byte, len(encryptedData))
+	sivCTR(ctrBlock, v).XORKeyStream(decryptedData, encryptedData)
+	if subtle.ConstantTimeCompare(s2v(macBlock, additionalData, decryptedData), v) != 1 {
+		return nil, fmt.Errorf("%w: synthetic IV mismatch", errors.ErrAuthenticationFailed)
+	}
+	return decryptedData, nil
+}
+
+func newSIVBlocks(key []byte) (cipher.Block, cipher.Block, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, nil, fmt.Errorf("invalid AES-SIV key size %d", len(key))
+	}
+	macBlock, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, nil, err
+	}
+	ctrBlock, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return macBlock, ctrBlock, nil
+}
+
+// sivCTR returns the AES-CTR stream of v, with the 31st and 63rd bit cleared
+// so implementations can use 32 or 64 bit counters (RFC 5297, section 2.6).
+func sivCTR(block cipher.Block, v []byte) cipher.Stream {
+	iv := make([]byte, sivSize)
+	copy(iv, v)
+	iv[8] &= 0x7f
+	iv[12] &= 0x7f
+	return cipher.NewCTR(block, iv)
+}
+
+// s2v implements S2V over the components of additionalData followed by
+// plaintext (RFC 5297, section 2.4).
+func s2v(block cipher.Block, additionalData [][]byte, plaintext []byte) []byte {
+	d := cmac(block, make([]byte, aes.BlockSize))
+	for _, component := range additionalData {
+		dbl(d)
+		xorBytes(d, cmac(block, component))
+	}
+
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		// xorend: xor d into the last block of the plaintext
+		t = append([]byte{}, plaintext...)
+		xorBytes(t[len(t)-aes.BlockSize:], d)
+	} else {
+		dbl(d)
+		t = pad(plaintext)
+		xorBytes(t, d)
+	}
+	return cmac(block, t)
+}
+
+// cmac computes AES-CMAC (RFC 4493) of msg.
+func cmac(block cipher.Block, msg []byte) []byte {
+	k1 := make([]byte, aes.BlockSize)
+	block.Encrypt(k1, k1)
+	dbl(k1)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	var last []byte
+	if n > 0 && len(msg)%aes.BlockSize == 0 {
+		last = append([]byte{}, msg[(n-1)*aes.BlockSize:]...)
+		xorBytes(last, k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		k2 := append([]byte{}, k1...)
+		dbl(k2)
+		last = pad(msg[(n-1)*aes.BlockSize:])
+		xorBytes(last, k2)
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		xorBytes(x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x, x)
+	}
+	xorBytes(x, last)
+	block.Encrypt(x, x)
+	return x
+}
+
+// dbl multiplies b by x in GF(2^128), in place.
+func dbl(b []byte) {
+	carry := b[0] >> 7
+	for i := 0; i < len(b)-1; i++ {
+		b[i] = b[i]<<1 | b[i+1]>>7
+	}
+	b[len(b)-1] = b[len(b)-1]<<1 ^ byte(subtle.ConstantTimeByteEq(carry, 1))*0x87
+}
+
+// pad appends the 10* padding to a partial block.
+func pad(b []byte) []byte {
+	padded := make([]byte, aes.BlockSize)
+	copy(padded, b)
+	padded[len(b)] = 0x80
+	return padded
+}
+
+func xorBytes(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
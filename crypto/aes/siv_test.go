@@ -0,0 +1,157 @@
+package aes
+
+import (
+	"bytes"
+	stdErrors "errors"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// TestSealSIV checks the test vectors of RFC 5297, appendix A.
+func TestSealSIV(t *testing.T) {
+	tests := []struct {
+		name           string
+		key            string
+		additionalData []string
+		plaintext      string
+		want           string
+	}{
+		{
+			name:           "A.1 Deterministic Authenticated Encryption",
+			key:            "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff",
+			additionalData: []string{"10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627"},
+			plaintext:      "11223344 55667788 99aabbcc ddee",
+			want:           "85632d07 c6e8f37f 950acd32 0a2ecc93 40c02b96 90c4dc04 daef7f6a fe5c",
+		},
+		{
+			name: "A.2 Nonce-Based Authenticated Encryption",
+			key:  "7f7e7d7c 7b7a7978 77767574 73727170 40414243 44454647 48494a4b 4c4d4e4f",
+			additionalData: []string{
+				"00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100",
+				"10203040 50607080 90a0",
+				"09f91102 9d74e35b d84156c5 635688c0",
+			},
+			plaintext: "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553",
+			want:      "7bdb6e3b 432667eb 06f4d14b ff2fbd0f cb900f2f ddbe4043 26601965 c889bf17 dba77ceb 094fa663 b7a3f748 ba8af829 ea64ad54 4a272e9c 485b62a3 fd5c0d",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := testutil.DecodeHex(t, tt.key)
+			additionalData := make([][]byte, 0, len(tt.additionalData))
+			for _, component := range tt.additionalData {
+				additionalData = append(additionalData, testutil.DecodeHex(t, component))
+			}
+			plaintext := testutil.DecodeHex(t, tt.plaintext)
+			want := testutil.DecodeHex(t, tt.want)
+
+			got, err := SealSIV(key, plaintext, additionalData...)
+			if err != nil {
+				t.Fatalf("SealSIV() error = %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("SealSIV() = %x, want %x", got, want)
+			}
+
+			decrypted, err := OpenSIV(key, want, additionalData...)
+			if err != nil {
+				t.Fatalf("OpenSIV() error = %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("OpenSIV() = %x, want %x", decrypted, plaintext)
+			}
+
+			tampered := bytes.Clone(want)
+			tampered[len(tampered)-1] ^= 1
+			if _, err := OpenSIV(key, tampered, additionalData...); !stdErrors.Is(err, errors.ErrAuthenticationFailed) {
+				t.Errorf("OpenSIV() error = %v, want %v", err, errors.ErrAuthenticationFailed)
+			}
+			if _, err := OpenSIV(key, want); !stdErrors.Is(err, errors.ErrAuthenticationFailed) {
+				t.Errorf("OpenSIV() without associated data error = %v, want %v", err, errors.ErrAuthenticationFailed)
+			}
+		})
+	}
+
+	if _, err := SealSIV(make([]byte, 16), []byte("data")); err == nil {
+		t.Errorf("SealSIV() error = nil, want an error for a 16 byte key")
+	}
+	if _, err := OpenSIV(make([]byte, 32), make([]byte, 15)); !stdErrors.Is(err, errors.ErrTruncatedData) {
+		t.Errorf("OpenSIV() error = %v, want %v", err, errors.ErrTruncatedData)
+	}
+}
+
+func TestEncryptDeterministic(t *testing.T) {
+	store := newTestSecretStore(t, models.KeyStateActive, models.KeyStateActive)
+
+	payload, err := EncryptDeterministicWithStore(store, "SECRET", []byte("jane@example.com"))
+	if err != nil {
+		t.Fatalf("EncryptDeterministicWithStore() error = %v", err)
+	}
+	if payload.KeyVersion != 2 || payload.KeyType != models.KeyTypeAESSIV {
+		t.Errorf("EncryptDeterministicWithStore() = %v, want version 2 of type %v", payload, models.KeyTypeAESSIV)
+	}
+
+	again, err := EncryptDeterministicWithStore(store, "SECRET", []byte("jane@example.com"))
+	if err != nil {
+		t.Fatalf("EncryptDeterministicWithStore() error = %v", err)
+	}
+	if again.String() != payload.String() {
+		t.Errorf("EncryptDeterministicWithStore() = %v, want the same ciphertext %v", again.String(), payload.String())
+	}
+	other, err := EncryptDeterministicWithStore(store, "SECRET", []byte("john@example.com"))
+	if err != nil {
+		t.Fatalf("EncryptDeterministicWithStore() error = %v", err)
+	}
+	if other.EncryptedData == payload.EncryptedData {
+		t.Errorf("EncryptDeterministicWithStore() gave equal ciphertexts for different values")
+	}
+
+	decrypted, err := DecryptNamedWithStore(store, payload.String())
+	if err != nil || decrypted.Data != "jane@example.com" || decrypted.KeyType != models.KeyTypeAESSIV {
+		t.Errorf("DecryptNamedWithStore() = %v, %v, want jane@example.com", decrypted, err)
+	}
+
+	// the ciphertext is bound to the key version
+	moved := models.ParsePayload(payload.String())
+	moved.KeyVersion = 1
+	if _, err := DecryptNamedWithStore(store, moved.String()); !stdErrors.Is(err, errors.ErrAuthenticationFailed) {
+		t.Errorf("DecryptNamedWithStore() error = %v, want %v", err, errors.ErrAuthenticationFailed)
+	}
+
+	if _, err := EncryptDeterministicWithStore(store, "MISSING", []byte("data")); !stdErrors.Is(err, errors.ErrKeyNotFound) {
+		t.Errorf("EncryptDeterministicWithStore() error = %v, want %v", err, errors.ErrKeyNotFound)
+	}
+	if _, err := EncryptDeterministicWithStore(store, "SECRET", nil); !stdErrors.Is(err, errors.ErrEmptyData) {
+		t.Errorf("EncryptDeterministicWithStore() error = %v, want %v", err, errors.ErrEmptyData)
+	}
+}
+
+func TestRotateDeterministic(t *testing.T) {
+	store := newTestSecretStore(t, models.KeyStateActive)
+	keyInfo := store.Get("SECRET", 1)
+	payload, err := encryptDeterministicWithKey(keyInfo, []byte("data"))
+	if err != nil {
+		t.Fatalf("encryptDeterministicWithKey() error = %v", err)
+	}
+	secret := make([]byte, 32)
+	secret[0] = 2
+	if err := store.Put(models.KeyInfo{Name: "SECRET", Version: 2, Secret: secret}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	rotated, ok, err := RotateWithStore(store, payload.String())
+	if err != nil || !ok {
+		t.Fatalf("RotateWithStore() = %v, %v, want a rotated value", ok, err)
+	}
+	parsed := models.ParsePayload(rotated)
+	if parsed == nil || parsed.KeyVersion != 2 || parsed.KeyType != models.KeyTypeAESSIV {
+		t.Errorf("RotateWithStore() = %v, want version 2 of type %v", parsed, models.KeyTypeAESSIV)
+	}
+	want, _ := EncryptDeterministicWithStore(store, "SECRET", []byte("data"))
+	if rotated != want.String() {
+		t.Errorf("RotateWithStore() = %v, want %v", rotated, want.String())
+	}
+}
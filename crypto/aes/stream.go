@@ -0,0 +1,174 @@
+package aes
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/nected/go-lib/crypto/errors"
+)
+
+// Stream format
+//
+//	version(1) || salt(16) || segment_0 || ... || segment_n
+//
+// Every segment holds streamSegmentSize bytes of plaintext, except the last
+// one which may be shorter or empty, sealed with AES-256-GCM under a key
+// derived from the secret and the random salt. The nonce of a segment is
+// 0(7) || index(4) || last(1), so reordering, dropping or appending segments
+// and truncating the stream at a segment boundary are all detected.
+const (
+	streamVersion     byte = 1
+	streamSaltSize         = 16
+	streamSegmentSize      = 64 * 1024
+	streamTagSize          = 16
+)
+
+// EncryptStream encrypts everything read from r with secret and writes the
+// result to w. Unlike Encrypt, memory use is bounded by the segment size, so
+// it is suited for files and large blobs.
+//
+// Parameters:
+//   - w: The writer that receives the encrypted stream.
+//   - r: The reader providing the data to encrypt.
+//   - secret: A raw AES key of 16, 24 or 32 bytes.
+//
+// Returns:
+//   - error: An error if reading, encrypting or writing fails.
+func EncryptStream(w io.Writer, r io.Reader, secret string) error {
+	if _, err := aes.NewCipher([]byte(secret)); err != nil {
+		return err
+	}
+
+	salt := make([]byte, streamSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := newGCM(deriveStreamKey([]byte(secret), salt))
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append([]byte{streamVersion}, salt...)); err != nil {
+		return err
+	}
+
+	// read one segment ahead so the last segment can be flagged as such
+	current := make([]byte, streamSegmentSize)
+	next := make([]byte, streamSegmentSize)
+	currentLen, err := io.ReadFull(r, current)
+	currentLast := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !currentLast {
+		return err
+	}
+
+	sealed := make([]byte, 0, streamSegmentSize+streamTagSize)
+	for index := uint32(0); ; index++ {
+		nextLen := 0
+		if !currentLast {
+			nextLen, err = io.ReadFull(r, next)
+			if err == io.EOF {
+				currentLast = true
+			} else if err != nil && err != io.ErrUnexpectedEOF {
+				return err
+			}
+		}
+
+		sealed = gcm.Seal(sealed[:0], streamNonce(index, currentLast), current[:currentLen], nil)
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if currentLast {
+			return nil
+		}
+		if index == ^uint32(0) {
+			return fmt.Errorf("stream exceeds %d segments", ^uint32(0))
+		}
+
+		current, next = next, current
+		currentLen = nextLen
+		currentLast = nextLen < streamSegmentSize
+	}
+}
+
+// DecryptStream decrypts a stream produced by EncryptStream with the same
+// secret and writes the plaintext to w.
+//
+// Segments are written to w as soon as they are authenticated, so when an
+// error is returned the data already written must be discarded.
+// errors.ErrTruncatedData is returned when the stream ends before its last
+// segment.
+func DecryptStream(w io.Writer, r io.Reader, secret string) error {
+	if _, err := aes.NewCipher([]byte(secret)); err != nil {
+		return err
+	}
+
+	header := make([]byte, 1+streamSaltSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("%w: stream header", errors.ErrTruncatedData)
+	}
+	if header[0] != streamVersion {
+		return fmt.Errorf("%w: unknown stream version %d", errors.ErrInvalidData, header[0])
+	}
+	gcm, err := newGCM(deriveStreamKey([]byte(secret), header[1:]))
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, streamSegmentSize+streamTagSize)
+	segment := make([]byte, streamSegmentSize+streamTagSize)
+	plain := make([]byte, 0, streamSegmentSize)
+	for index := uint32(0); ; index++ {
+		n, err := io.ReadFull(br, segment)
+		if err == io.EOF {
+			return fmt.Errorf("%w: missing last segment", errors.ErrTruncatedData)
+		}
+		last := err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		if !last {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+
+		plain, err = gcm.Open(plain[:0], streamNonce(index, last), segment[:n], nil)
+		if err != nil {
+			// a segment that only opens as a middle segment means the stream
+			// was cut right after it
+			if _, middleErr := gcm.Open(nil, streamNonce(index, false), segment[:n], nil); last && middleErr == nil {
+				return fmt.Errorf("%w: missing last segment", errors.ErrTruncatedData)
+			}
+			return fmt.Errorf("%w: segment %d", errors.ErrInvalidData, index)
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// deriveStreamKey derives the AES-256 key of a single stream from the secret
+// and the stream salt.
+func deriveStreamKey(secret, salt []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("go-lib/crypto/aes stream"))
+	mac.Write(salt)
+	return mac.Sum(nil)
+}
+
+func streamNonce(index uint32, last bool) []byte {
+	nonce := make([]byte, nonceSize)
+	binary.BigEndian.PutUint32(nonce[7:11], index)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,137 @@
+package aes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	cryptoErrors "github.com/nected/go-lib/crypto/errors"
+)
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("rand.Read() error = %v", err)
+	}
+	return data
+}
+
+func TestEncryptStream(t *testing.T) {
+	secret := "someRandomSecret"
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "empty", size: 0},
+		{name: "small", size: 100},
+		{name: "exactly one segment", size: streamSegmentSize},
+		{name: "segment boundary", size: 3 * streamSegmentSize},
+		{name: "several segments", size: 3*streamSegmentSize + 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := randomBytes(t, tt.size)
+			var encrypted bytes.Buffer
+			if err := EncryptStream(&encrypted, bytes.NewReader(data), secret); err != nil {
+				t.Fatalf("EncryptStream() error = %v", err)
+			}
+
+			var decrypted bytes.Buffer
+			if err := DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes()), secret); err != nil {
+				t.Fatalf("DecryptStream() error = %v", err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), data) {
+				t.Errorf("DecryptStream() data mismatch")
+			}
+		})
+	}
+}
+
+func TestDecryptStreamTampered(t *testing.T) {
+	secret := "someRandomSecret"
+	data := randomBytes(t, 2*streamSegmentSize+10)
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader(data), secret); err != nil {
+		t.Fatalf("EncryptStream() error = %v", err)
+	}
+	stream := encrypted.Bytes()
+	headerSize := 1 + streamSaltSize
+	sealedSegmentSize := streamSegmentSize + streamTagSize
+
+	tests := []struct {
+		name    string
+		stream  []byte
+		secret  string
+		wantErr error
+	}{
+		{
+			name:    "truncated at segment boundary",
+			stream:  stream[:headerSize+2*sealedSegmentSize],
+			secret:  secret,
+			wantErr: cryptoErrors.ErrTruncatedData,
+		},
+		{
+			name:    "truncated inside segment",
+			stream:  stream[:headerSize+sealedSegmentSize+100],
+			secret:  secret,
+			wantErr: cryptoErrors.ErrInvalidData,
+		},
+		{
+			name:    "header only",
+			stream:  stream[:headerSize],
+			secret:  secret,
+			wantErr: cryptoErrors.ErrTruncatedData,
+		},
+		{
+			name:    "short header",
+			stream:  stream[:4],
+			secret:  secret,
+			wantErr: cryptoErrors.ErrTruncatedData,
+		},
+		{
+			name: "flipped bit",
+			stream: func() []byte {
+				tampered := append([]byte{}, stream...)
+				tampered[headerSize+10] ^= 1
+				return tampered
+			}(),
+			secret:  secret,
+			wantErr: cryptoErrors.ErrInvalidData,
+		},
+		{
+			name: "reordered segments",
+			stream: func() []byte {
+				tampered := append([]byte{}, stream[:headerSize]...)
+				tampered = append(tampered, stream[headerSize+sealedSegmentSize:headerSize+2*sealedSegmentSize]...)
+				tampered = append(tampered, stream[headerSize:headerSize+sealedSegmentSize]...)
+				return append(tampered, stream[headerSize+2*sealedSegmentSize:]...)
+			}(),
+			secret:  secret,
+			wantErr: cryptoErrors.ErrInvalidData,
+		},
+		{
+			name:    "wrong secret",
+			stream:  stream,
+			secret:  "anotherSecret123",
+			wantErr: cryptoErrors.ErrInvalidData,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decrypted bytes.Buffer
+			err := DecryptStream(&decrypted, bytes.NewReader(tt.stream), tt.secret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("DecryptStream() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptStreamInvalidSecret(t *testing.T) {
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader([]byte("data")), "1"); err == nil {
+		t.Errorf("EncryptStream() error = nil, want invalid key size")
+	}
+}
@@ -13,7 +13,7 @@ func B64Encode(data []byte) string {
 func B64Decode(data string) (string, error) {
 	decodedData, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
-		return "", errors.ErrInvalidData
+		return "", &errors.DecodeError{Encoding: "base64", Err: err}
 	}
 	return string(decodedData), nil
 }
@@ -25,7 +25,21 @@ func B64EncodeURL(data []byte) string {
 func B64DecodeURL(data string) (string, error) {
 	decodedData, err := base64.URLEncoding.DecodeString(data)
 	if err != nil {
-		return "", errors.ErrInvalidData
+		return "", &errors.DecodeError{Encoding: "base64url", Err: err}
+	}
+	return string(decodedData), nil
+}
+
+// B64EncodeRawURL encodes data with the unpadded URL-safe alphabet used by
+// JWTs.
+func B64EncodeRawURL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func B64DecodeRawURL(data string) (string, error) {
+	decodedData, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return "", &errors.DecodeError{Encoding: "unpadded base64url", Err: err}
 	}
 	return string(decodedData), nil
 }
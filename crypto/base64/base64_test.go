@@ -1,7 +1,11 @@
 package base64
 
 import (
+	"encoding/base64"
+	stdErrors "errors"
 	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
 )
 
 func TestB64Encode(t *testing.T) {
@@ -87,3 +91,13 @@ func TestB64DecodeURL(t *testing.T) {
 	}
 }
 
+func TestB64DecodeError(t *testing.T) {
+	_, err := B64Decode("invalid_base64")
+	if !stdErrors.Is(err, errors.ErrInvalidData) {
+		t.Errorf("B64Decode() error = %v, want %v", err, errors.ErrInvalidData)
+	}
+	var corruptInputErr base64.CorruptInputError
+	if !stdErrors.As(err, &corruptInputErr) || corruptInputErr != 7 {
+		t.Errorf("B64Decode() error = %v, want the base64.CorruptInputError at offset 7", err)
+	}
+}
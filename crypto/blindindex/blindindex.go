@@ -0,0 +1,141 @@
+package blindindex
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Token format
+//
+//	base64("$keyName$keyVersion$bidx:" + base64(HMAC-SHA256(indexKey, value)))
+//
+// Index keys are named, versioned secrets loaded like the AES secrets, e.g.
+// with config.LoadSecretsFromEnv. The HMAC key is derived from the secret,
+// so a secret shared with aes.EncryptNamed still yields an independent key.
+//
+// Unlike rsa.Encrypt and aes.Encrypt, Token is deterministic: the same value
+// and index key version always produce the same token, which is stored next
+// to the ciphertext and queried by equality.
+
+// hkdfInfo separates index keys from any other key derived from a secret.
+const hkdfInfo = "go-lib blind index"
+
+// Token returns the blind index token of value under the latest active
+// version of the index key keyName.
+//
+// Parameters:
+//   - keyName: The name of the index key.
+//   - value: The plaintext value to index.
+//   - opts: WithStore, WithLength and WithNormalizers. The same options must
+//     be used for every token of a field.
+//
+// Returns:
+//   - string: The token, carrying the key name and version.
+//   - error: errors.ErrKeyNotFound if the key is not loaded.
+func Token(keyName, value string, opts ...Option) (string, error) {
+	o := newOptions(opts)
+	keyInfo, err := models.FindKey(o.store, keyName, 0)
+	if err != nil {
+		return "", err
+	}
+	return token(keyInfo, value, o)
+}
+
+// Tokens returns the tokens of value under every version of the index key
+// keyName that is not revoked, oldest first. While records are re-indexed
+// with a new version a lookup queries all of them, e.g. with Mongo's $in.
+func Tokens(keyName, value string, opts ...Option) ([]string, error) {
+	o := newOptions(opts)
+	tokens := make([]string, 0)
+	for _, keyInfo := range o.store.List() {
+		keyInfo := keyInfo
+		if keyInfo.GetName() != keyName || !keyInfo.CanDecrypt() {
+			continue
+		}
+		t, err := token(&keyInfo, value, o)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if len(tokens) == 0 {
+		return nil, &errors.KeyError{KeyName: keyName, Err: errors.ErrKeyNotFound}
+	}
+	return tokens, nil
+}
+
+// Match reports whether t is the token of value. The key version and length
+// are read from t; only the WithStore and WithNormalizers options are used.
+//
+// Returns errors.ErrInvalidData if t is not a token, errors.ErrKeyNotFound if
+// its key version is not loaded and errors.ErrKeyRevoked if it was revoked.
+func Match(t, value string, opts ...Option) (bool, error) {
+	o := newOptions(opts)
+	parsed := models.ParsePayload(t)
+	if parsed == nil || parsed.KeyType != models.KeyTypeBlindIndex {
+		return false, errors.ErrInvalidData
+	}
+	mac, err := base64.B64Decode(parsed.EncryptedData)
+	if err != nil {
+		return false, err
+	}
+	o.length = len(mac)
+	keyInfo, err := models.FindDecryptionKey(o.store, parsed.KeyName, parsed.KeyVersion)
+	if err != nil {
+		return false, err
+	}
+	want, err := sum(keyInfo, value, o)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(mac), want), nil
+}
+
+func token(keyInfo *models.KeyInfo, value string, o *options) (string, error) {
+	mac, err := sum(keyInfo, value, o)
+	if err != nil {
+		return "", err
+	}
+	p := models.Payload{
+		KeyName:       keyInfo.GetName(),
+		KeyVersion:    keyInfo.GetVersion(),
+		KeyType:       models.KeyTypeBlindIndex,
+		EncryptedData: base64.B64Encode(mac),
+	}
+	return p.String(), nil
+}
+
+// sum returns the truncated HMAC of the normalized value.
+func sum(keyInfo *models.KeyInfo, value string, o *options) ([]byte, error) {
+	if o.length < 1 || o.length > sha256.Size {
+		return nil, fmt.Errorf("invalid blind index length %d", o.length)
+	}
+	key, err := indexKey(keyInfo)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(o.normalize(value)))
+	return h.Sum(nil)[:o.length], nil
+}
+
+// indexKey derives the HMAC key of a version of an index key from its secret.
+func indexKey(keyInfo *models.KeyInfo) ([]byte, error) {
+	secret := keyInfo.GetSecret()
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%w: %s has no secret", errors.ErrInvalidKeyInfo, keyInfo.KeyNameVersion())
+	}
+	info := append([]byte(hkdfInfo+"\x00"), keyInfo.Label(nil)...)
+	key := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
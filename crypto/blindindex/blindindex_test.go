@@ -0,0 +1,137 @@
+package blindindex
+
+import (
+	stdErrors "errors"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func TestToken(t *testing.T) {
+	store := testutil.NewSecretStore(t, "EMAILIDX", 2)
+	email := WithNormalizers(TrimSpace, Lowercase)
+
+	token, err := Token("EMAILIDX", "Jane@Example.com ", WithStore(store), email)
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	parsed := models.ParsePayload(token)
+	if parsed == nil || parsed.KeyVersion != 2 || parsed.KeyType != models.KeyTypeBlindIndex {
+		t.Fatalf("ParsePayload() = %v, want a blind index token of version 2", parsed)
+	}
+
+	tests := []struct {
+		name      string
+		value     string
+		opts      []Option
+		wantEqual bool
+	}{
+		{"Same value", "Jane@Example.com ", []Option{email}, true},
+		{"Normalized value", "jane@example.com", []Option{email}, true},
+		{"Other value", "john@example.com", []Option{email}, false},
+		{"Not normalized", "Jane@Example.com", nil, false},
+		{"Truncated", "jane@example.com", []Option{email, WithLength(8)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Token("EMAILIDX", tt.value, append(tt.opts, WithStore(store))...)
+			if err != nil {
+				t.Fatalf("Token() error = %v", err)
+			}
+			if (got == token) != tt.wantEqual {
+				t.Errorf("Token() = %v, equal to %v = %v, want %v", got, token, got == token, tt.wantEqual)
+			}
+		})
+	}
+
+	// each version yields its own token
+	other := testutil.NewSecretStore(t, "EMAILIDX", 3)
+	if got, _ := Token("EMAILIDX", "jane@example.com", WithStore(other), email); got == token {
+		t.Errorf("Token() with version 3 = %v, want a token other than version 2", got)
+	}
+
+	if _, err := Token("MISSING", "value", WithStore(store)); !stdErrors.Is(err, errors.ErrKeyNotFound) {
+		t.Errorf("Token() error = %v, want %v", err, errors.ErrKeyNotFound)
+	}
+	for _, length := range []int{0, 33} {
+		if _, err := Token("EMAILIDX", "value", WithStore(store), WithLength(length)); err == nil {
+			t.Errorf("Token() with length %d error = nil, want an error", length)
+		}
+	}
+	noSecret := models.NewMemoryKeyStore()
+	noSecret.Put(models.KeyInfo{Name: "RSAKEY", Version: 1})
+	if _, err := Token("RSAKEY", "value", WithStore(noSecret)); !stdErrors.Is(err, errors.ErrInvalidKeyInfo) {
+		t.Errorf("Token() error = %v, want %v", err, errors.ErrInvalidKeyInfo)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	store := testutil.NewSecretStore(t, "EMAILIDX", 3)
+	if err := models.SetKeyState(store, "EMAILIDX", 1, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if err := models.SetKeyState(store, "EMAILIDX", 2, models.KeyStateDecryptOnly); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+
+	tokens, err := Tokens("EMAILIDX", "jane@example.com", WithStore(store), WithLength(16))
+	if err != nil {
+		t.Fatalf("Tokens() error = %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("Tokens() = %v, want the tokens of versions 2 and 3", tokens)
+	}
+	for i, want := range []int{2, 3} {
+		if parsed := models.ParsePayload(tokens[i]); parsed == nil || parsed.KeyVersion != want {
+			t.Errorf("Tokens()[%d] = %v, want version %d", i, parsed, want)
+		}
+	}
+	latest, _ := Token("EMAILIDX", "jane@example.com", WithStore(store), WithLength(16))
+	if tokens[1] != latest {
+		t.Errorf("Tokens()[1] = %v, want the Token() of the latest version %v", tokens[1], latest)
+	}
+
+	if _, err := Tokens("MISSING", "value", WithStore(store)); !stdErrors.Is(err, errors.ErrKeyNotFound) {
+		t.Errorf("Tokens() error = %v, want %v", err, errors.ErrKeyNotFound)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	store := testutil.NewSecretStore(t, "EMAILIDX", 1)
+	token, err := Token("EMAILIDX", "jane@example.com", WithStore(store), WithLength(8))
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	unknown := models.ParsePayload(token)
+	unknown.KeyVersion = 5
+
+	tests := []struct {
+		name    string
+		token   string
+		value   string
+		want    bool
+		wantErr error
+	}{
+		{"Matching value", token, "jane@example.com", true, nil},
+		{"Other value", token, "john@example.com", false, nil},
+		{"Not a token", "jane@example.com", "jane@example.com", false, errors.ErrInvalidData},
+		{"Unknown version", unknown.String(), "jane@example.com", false, errors.ErrKeyNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Match(tt.token, tt.value, WithStore(store))
+			if !stdErrors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Match() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	if err := models.SetKeyState(store, "EMAILIDX", 1, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if _, err := Match(token, "jane@example.com", WithStore(store)); !stdErrors.Is(err, errors.ErrKeyRevoked) {
+		t.Errorf("Match() error = %v, want %v", err, errors.ErrKeyRevoked)
+	}
+}
@@ -0,0 +1,81 @@
+package blindindex
+
+import (
+	"crypto/sha256"
+	"strings"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// Normalizer rewrites a value before it is indexed, so that values which
+// should match produce the same token.
+type Normalizer func(string) string
+
+var (
+	// TrimSpace removes leading and trailing white space.
+	TrimSpace Normalizer = strings.TrimSpace
+	// Lowercase maps the value to lower case, e.g. for email addresses.
+	Lowercase Normalizer = strings.ToLower
+)
+
+type options struct {
+	store       models.KeyStore
+	length      int
+	normalizers []Normalizer
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		store:  models.DefaultSecretStore(),
+		length: sha256.Size,
+	}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	return o
+}
+
+func (o *options) normalize(value string) string {
+	for _, normalizer := range o.normalizers {
+		value = normalizer(value)
+	}
+	return value
+}
+
+type Option interface {
+	apply(*options)
+}
+
+// optionFunc wraps a func so it satisfies the Option interface.
+type optionFunc func(*options)
+
+func (f optionFunc) apply(o *options) {
+	f(o)
+}
+
+// WithStore looks index keys up in store instead of the default secret
+// store.
+func WithStore(store models.KeyStore) Option {
+	return optionFunc(func(o *options) {
+		o.store = store
+	})
+}
+
+// WithLength truncates tokens to length bytes, between 1 and 32. Shorter
+// tokens make different values share a token, so a query also returns some
+// records that do not match, which the caller filters out after decrypting;
+// in exchange the index reveals less about which records hold equal values.
+// The default is 32, the full HMAC-SHA256.
+func WithLength(length int) Option {
+	return optionFunc(func(o *options) {
+		o.length = length
+	})
+}
+
+// WithNormalizers applies normalizers in order to the value before it is
+// indexed, e.g. WithNormalizers(TrimSpace, Lowercase) for email addresses.
+func WithNormalizers(normalizers ...Normalizer) Option {
+	return optionFunc(func(o *options) {
+		o.normalizers = append(o.normalizers, normalizers...)
+	})
+}
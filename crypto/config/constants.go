@@ -1,6 +1,15 @@
 package config
 
 const (
-	KEY_ENV_PREFIX = "ENCRYPTKEY"
-	PRIV_KEY_TYPE  = "PRIVATE KEY"
+	KEY_ENV_PREFIX    = "ENCRYPTKEY"
+	SECRET_ENV_PREFIX = "ENCRYPTSECRET"
+	PASS_ENV_PREFIX   = "ENCRYPTPASS"
+	PRIV_KEY_TYPE     = "PRIVATE KEY"
+	ENC_PRIV_KEY_TYPE = "ENCRYPTED PRIVATE KEY"
+	RSA_PRIV_KEY_TYPE = "RSA PRIVATE KEY"
+	EC_PRIV_KEY_TYPE  = "EC PRIVATE KEY"
+	PUB_KEY_TYPE      = "PUBLIC KEY"
+	RSA_PUB_KEY_TYPE  = "RSA PUBLIC KEY"
+	CERT_TYPE         = "CERTIFICATE"
+	KEY_FILE_EXT      = ".pem"
 )
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// KeyFileError reports a key file that could not be loaded.
+type KeyFileError struct {
+	Path string
+	Err  error
+}
+
+func (e *KeyFileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *KeyFileError) Unwrap() error {
+	return e.Err
+}
+
+// KeyDirError aggregates the errors of every key file in a directory that
+// could not be loaded. Keys from the other files are still registered.
+type KeyDirError struct {
+	Dir   string
+	Files []*KeyFileError
+}
+
+func (e *KeyDirError) Error() string {
+	msgs := make([]string, 0, len(e.Files))
+	for _, fileErr := range e.Files {
+		msgs = append(msgs, fileErr.Error())
+	}
+	return fmt.Sprintf("failed to load %d key file(s) from %s: %s", len(e.Files), e.Dir, strings.Join(msgs, "; "))
+}
+
+func (e *KeyDirError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Files))
+	for _, fileErr := range e.Files {
+		errs = append(errs, fileErr)
+	}
+	return errs
+}
+
+// key directory format
+//
+// <dir>/<key_name>_<key_version>.pem
+//
+// Parameters:
+//   - key_name: name of the key
+//   - key_version(optional): version of the key, default is 1
+//
+// Example:
+//   - keys/TESTKEY_1.pem
+//   - keys/TESTKEY_2.pem
+//
+// Files without the .pem extension and sub directories are ignored.
+func LoadKeysFromDir(dir string) error {
+	return LoadKeysFromDirToStore(models.DefaultKeyStore(), dir)
+}
+
+// LoadKeysFromDirToStore loads every key file in dir into store. See
+// LoadKeysFromDir for the file naming format.
+func LoadKeysFromDirToStore(store models.KeyStore, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	dirErr := &KeyDirError{Dir: dir}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != KEY_FILE_EXT {
+			continue
+		}
+		keyPath := filepath.Join(dir, entry.Name())
+		if err := loadKeyFileToStore(store, keyPath); err != nil {
+			dirErr.Files = append(dirErr.Files, &KeyFileError{Path: keyPath, Err: err})
+		}
+	}
+
+	if len(dirErr.Files) > 0 {
+		return dirErr
+	}
+	return nil
+}
+
+// defaultWatchInterval is the poll interval of WatchKeysDir when the given
+// one is not positive.
+const defaultWatchInterval = 30 * time.Second
+
+// WatchKeysDir loads dir into store and then polls it every interval,
+// loading key files that were added or modified since the last poll.
+// Keys of removed files stay in the store; use Reload to drop them. An
+// interval that is not positive polls every 30 seconds.
+//
+// Errors of every poll are passed to onError, which may be nil. WatchKeysDir
+// blocks until ctx is done, so it is usually run in its own goroutine.
+func WatchKeysDir(ctx context.Context, store models.KeyStore, dir string, interval time.Duration, onError func(error)) {
+	if onError == nil {
+		onError = func(error) {}
+	}
+	seen := make(map[string]keyFileStamp)
+
+	poll := func() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			onError(err)
+			return
+		}
+		dirErr := &KeyDirError{Dir: dir}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != KEY_FILE_EXT {
+				continue
+			}
+			keyPath := filepath.Join(dir, entry.Name())
+			fileInfo, err := entry.Info()
+			if err != nil {
+				dirErr.Files = append(dirErr.Files, &KeyFileError{Path: keyPath, Err: err})
+				continue
+			}
+			stamp := keyFileStamp{modTime: fileInfo.ModTime(), size: fileInfo.Size()}
+			if prev, ok := seen[keyPath]; ok && prev == stamp {
+				continue
+			}
+			if err := loadKeyFileToStore(store, keyPath); err != nil {
+				dirErr.Files = append(dirErr.Files, &KeyFileError{Path: keyPath, Err: err})
+				continue
+			}
+			seen[keyPath] = stamp
+		}
+		if len(dirErr.Files) > 0 {
+			onError(dirErr)
+		}
+	}
+
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	poll()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			poll()
+		}
+	}
+}
+
+type keyFileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func loadKeyFileToStore(store models.KeyStore, keyPath string) error {
+	keyName, keyVersion, err := parseKeyFileName(filepath.Base(keyPath))
+	if err != nil {
+		return err
+	}
+	keyInfo, err := loadKeyFromFile(keyPath, keyPassphrase(keyName, keyVersion))
+	if err != nil {
+		return err
+	}
+	keyInfo.SetName(keyName)
+	keyInfo.SetVersion(keyVersion)
+	return store.Put(keyInfo)
+}
+
+// parseKeyFileName splits a file name of the form <key_name>_<key_version>.pem
+// into its key name and version. The version defaults to 1 when missing, so
+// when the part after the last underscore is not a number it is kept in the
+// key name.
+func parseKeyFileName(fileName string) (string, int, error) {
+	base := strings.TrimSuffix(fileName, KEY_FILE_EXT)
+	keyName, keyVersion := base, 1
+	if idx := strings.LastIndex(base, "_"); idx >= 0 {
+		version, err := strconv.Atoi(base[idx+1:])
+		if err == nil {
+			if version < 1 {
+				return "", 0, fmt.Errorf("invalid key version %q", base[idx+1:])
+			}
+			keyName, keyVersion = base[:idx], version
+		}
+	}
+	if keyName == "" {
+		return "", 0, fmt.Errorf("key name is missing")
+	}
+	return keyName, keyVersion, nil
+}
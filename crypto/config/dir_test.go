@@ -0,0 +1,177 @@
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func generatePrivateKey() string {
+	privatekey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		fmt.Printf("Cannot generate RSA key\n")
+		os.Exit(1)
+	}
+
+	// encode private key to PEM format
+	privatekeyBytes, err := x509.MarshalPKCS8PrivateKey(privatekey)
+	if err != nil {
+		fmt.Printf("Cannot marshal private key to bytes\n")
+		return ""
+	}
+
+	privateKeyBlock := &pem.Block{
+		Type:  PRIV_KEY_TYPE,
+		Bytes: privatekeyBytes,
+	}
+
+	return string(pem.EncodeToMemory(privateKeyBlock))
+}
+
+func writeKeyFile(t *testing.T, dir, fileName, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, fileName), []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func Test_parseKeyFileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		wantName    string
+		wantVersion int
+		wantErr     bool
+	}{
+		{"name and version", "TESTKEY_2.pem", "TESTKEY", 2, false},
+		{"name only", "TESTKEY.pem", "TESTKEY", 1, false},
+		{"underscore in name", "TEST_KEY_3.pem", "TEST_KEY", 3, false},
+		{"underscore without version", "MY_KEY.pem", "MY_KEY", 1, false},
+		{"non numeric suffix", "TESTKEY_abc.pem", "TESTKEY_abc", 1, false},
+		{"zero version", "TESTKEY_0.pem", "", 0, true},
+		{"missing name", "_1.pem", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotName, gotVersion, err := parseKeyFileName(tt.fileName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseKeyFileName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotName != tt.wantName || gotVersion != tt.wantVersion {
+				t.Errorf("parseKeyFileName() = %v, %v, want %v, %v", gotName, gotVersion, tt.wantName, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestLoadKeysFromDirToStore(t *testing.T) {
+	dir := t.TempDir()
+	privateKey := generatePrivateKey()
+	writeKeyFile(t, dir, "DIRKEY_1.pem", privateKey)
+	writeKeyFile(t, dir, "DIRKEY_2.pem", privateKey)
+	writeKeyFile(t, dir, "OTHERKEY.pem", privateKey)
+	writeKeyFile(t, dir, "MY_KEY.pem", privateKey)
+	writeKeyFile(t, dir, "BROKEN_1.pem", "not a key")
+	writeKeyFile(t, dir, "BADVERSION_0.pem", privateKey)
+	writeKeyFile(t, dir, "README.md", "ignored")
+
+	store := models.NewMemoryKeyStore()
+	err := LoadKeysFromDirToStore(store, dir)
+
+	var dirErr *KeyDirError
+	if !errors.As(err, &dirErr) {
+		t.Fatalf("LoadKeysFromDirToStore() error = %v, want *KeyDirError", err)
+	}
+	if len(dirErr.Files) != 2 {
+		t.Errorf("LoadKeysFromDirToStore() failed files = %v, want 2", len(dirErr.Files))
+	}
+	var fileErr *KeyFileError
+	if !errors.As(err, &fileErr) {
+		t.Errorf("LoadKeysFromDirToStore() error = %v, want a *KeyFileError inside", err)
+	}
+
+	for _, want := range []struct {
+		name    string
+		version int
+	}{{"DIRKEY", 1}, {"DIRKEY", 2}, {"OTHERKEY", 1}, {"MY_KEY", 1}} {
+		if store.Get(want.name, want.version) == nil {
+			t.Errorf("key %s version %d not loaded", want.name, want.version)
+		}
+	}
+	if got := len(store.List()); got != 4 {
+		t.Errorf("List() = %v keys, want 4", got)
+	}
+}
+
+func TestWatchKeysDir(t *testing.T) {
+	dir := t.TempDir()
+	privateKey := generatePrivateKey()
+	writeKeyFile(t, dir, "WATCHKEY_1.pem", privateKey)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store := models.NewMemoryKeyStore()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		WatchKeysDir(ctx, store, dir, 10*time.Millisecond, func(err error) {
+			t.Errorf("WatchKeysDir() error = %v", err)
+		})
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.Get("WATCHKEY", 1) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("WATCHKEY version 1 not loaded on start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	writeKeyFile(t, dir, "WATCHKEY_2.pem", privateKey)
+	for store.Get("WATCHKEY", 2) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("WATCHKEY version 2 not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if latest := store.Latest("WATCHKEY"); latest.GetVersion() != 2 {
+		t.Errorf("Latest() version = %v, want 2", latest.GetVersion())
+	}
+}
+
+func TestWatchKeysDirZeroInterval(t *testing.T) {
+	dir := t.TempDir()
+	writeKeyFile(t, dir, "WATCHKEY_1.pem", generatePrivateKey())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store := models.NewMemoryKeyStore()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		WatchKeysDir(ctx, store, dir, 0, nil)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.Get("WATCHKEY", 1) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("WATCHKEY version 1 not loaded on start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-stopped
+}
@@ -1,41 +1,171 @@
 package config
 
 import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"os"
+
+	"github.com/nected/go-lib/crypto/models"
 )
 
-func loadPrivateKeyFromFile(keyPath string) (*rsa.PrivateKey, error) {
+func loadKeyFromFile(keyPath string, passphrase func() ([]byte, error)) (models.KeyInfo, error) {
 	fileData, err := os.ReadFile(keyPath)
 	if err != nil {
-		return nil, err
+		return models.KeyInfo{}, err
 	}
-	return loadPrivateKey(fileData)
+	return loadKey(fileData, passphrase)
 }
 
-func loadPrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
-	// Parse the key
-	privKeyPem, _ := pem.Decode(pemData)
-	if privKeyPem == nil {
-		return nil, fmt.Errorf("Invalid private key")
+// loadKey parses a PEM encoded private key, public key or X.509 certificate
+// into a KeyInfo without name and version.
+//
+// Private keys may be PKCS#8 (PRIVATE KEY), encrypted PKCS#8 (ENCRYPTED
+// PRIVATE KEY), PKCS#1 (RSA PRIVATE KEY) or SEC 1 (EC PRIVATE KEY); the last
+// two may be encrypted with legacy PEM encryption. passphrase is only called
+// for encrypted keys. Public keys may be PKIX (PUBLIC KEY) or PKCS#1 (RSA
+// PUBLIC KEY); keys loaded from them and from certificates only encrypt and
+// verify.
+func loadKey(pemData []byte, passphrase func() ([]byte, error)) (models.KeyInfo, error) {
+	keyPem, _ := pem.Decode(pemData)
+	if keyPem == nil {
+		return models.KeyInfo{}, fmt.Errorf("Invalid key")
 	}
-	if privKeyPem.Type != PRIV_KEY_TYPE {
-		return nil, fmt.Errorf("Invalid private key type")
+
+	der := keyPem.Bytes
+	// legacy PEM encryption is deprecated as insecure, but keys encrypted with
+	// it are still around
+	if x509.IsEncryptedPEMBlock(keyPem) {
+		password, err := passphrase()
+		if err != nil {
+			return models.KeyInfo{}, err
+		}
+		if der, err = x509.DecryptPEMBlock(keyPem, password); err != nil {
+			return models.KeyInfo{}, err
+		}
 	}
 
-	privKeyParsed, err := x509.ParsePKCS8PrivateKey(privKeyPem.Bytes)
+	var privateKey any
+	var err error
+	switch keyPem.Type {
+	case PRIV_KEY_TYPE:
+		privateKey, err = x509.ParsePKCS8PrivateKey(der)
+	case ENC_PRIV_KEY_TYPE:
+		var password []byte
+		if password, err = passphrase(); err != nil {
+			return models.KeyInfo{}, err
+		}
+		if der, err = decryptPKCS8(der, password); err != nil {
+			return models.KeyInfo{}, err
+		}
+		privateKey, err = x509.ParsePKCS8PrivateKey(der)
+	case RSA_PRIV_KEY_TYPE:
+		privateKey, err = x509.ParsePKCS1PrivateKey(der)
+	case EC_PRIV_KEY_TYPE:
+		privateKey, err = x509.ParseECPrivateKey(der)
+	case PUB_KEY_TYPE, RSA_PUB_KEY_TYPE, CERT_TYPE:
+		publicKey, err := loadPublicKey(keyPem.Type, der)
+		if err != nil {
+			return models.KeyInfo{}, err
+		}
+		return newPublicKeyInfo(publicKey)
+	default:
+		return models.KeyInfo{}, fmt.Errorf("Invalid key type %q", keyPem.Type)
+	}
 	if err != nil {
-		return nil, err
+		return models.KeyInfo{}, err
+	}
+	return newPrivateKeyInfo(privateKey)
+}
+
+func newPrivateKeyInfo(privateKey any) (models.KeyInfo, error) {
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if err := privateKey.Validate(); err != nil {
+			return models.KeyInfo{}, err
+		}
+		if err := checkRSAKeySize(&privateKey.PublicKey); err != nil {
+			return models.KeyInfo{}, err
+		}
+		return models.KeyInfo{
+			Algorithm: models.KeyAlgorithmRSA,
+			PrivKey:   privateKey,
+			PubKey:    generatePublicKey(privateKey),
+		}, nil
+	case *ecdsa.PrivateKey:
+		return models.KeyInfo{
+			Algorithm:  models.KeyAlgorithmECDSA,
+			PrivateKey: privateKey,
+			PublicKey:  &privateKey.PublicKey,
+		}, nil
+	case ed25519.PrivateKey:
+		return models.KeyInfo{
+			Algorithm:  models.KeyAlgorithmEd25519,
+			PrivateKey: privateKey,
+			PublicKey:  privateKey.Public(),
+		}, nil
+	case *ecdh.PrivateKey:
+		if privateKey.Curve() != ecdh.X25519() {
+			break
+		}
+		return models.KeyInfo{
+			Algorithm:  models.KeyAlgorithmX25519,
+			PrivateKey: privateKey,
+			PublicKey:  privateKey.PublicKey(),
+		}, nil
+	}
+	return models.KeyInfo{}, fmt.Errorf("Invalid private key type %T", privateKey)
+}
+
+// minRSAKeyBits is the smallest RSA modulus the loaders accept.
+const minRSAKeyBits = 2048
+
+// checkRSAKeySize rejects RSA keys shorter than minRSAKeyBits.
+func checkRSAKeySize(publicKey *rsa.PublicKey) error {
+	if bits := publicKey.N.BitLen(); bits < minRSAKeyBits {
+		return fmt.Errorf("RSA key of %d bits, want at least %d", bits, minRSAKeyBits)
 	}
-	privateKey, ok := privKeyParsed.(*rsa.PrivateKey)
-	if !ok {
-		return nil, fmt.Errorf("Invalid private key")
+	return nil
+}
+
+func newPublicKeyInfo(publicKey any) (models.KeyInfo, error) {
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		if err := checkRSAKeySize(publicKey); err != nil {
+			return models.KeyInfo{}, err
+		}
+		return models.KeyInfo{Algorithm: models.KeyAlgorithmRSA, PubKey: publicKey}, nil
+	case *ecdsa.PublicKey:
+		return models.KeyInfo{Algorithm: models.KeyAlgorithmECDSA, PublicKey: publicKey}, nil
+	case ed25519.PublicKey:
+		return models.KeyInfo{Algorithm: models.KeyAlgorithmEd25519, PublicKey: publicKey}, nil
+	case *ecdh.PublicKey:
+		if publicKey.Curve() != ecdh.X25519() {
+			break
+		}
+		return models.KeyInfo{Algorithm: models.KeyAlgorithmX25519, PublicKey: publicKey}, nil
 	}
+	return models.KeyInfo{}, fmt.Errorf("Invalid public key type %T", publicKey)
+}
 
-	return privateKey, nil
+func loadPublicKey(blockType string, der []byte) (any, error) {
+	switch blockType {
+	case PUB_KEY_TYPE:
+		return x509.ParsePKIXPublicKey(der)
+	case RSA_PUB_KEY_TYPE:
+		return x509.ParsePKCS1PublicKey(der)
+	case CERT_TYPE:
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("Invalid public key type")
 }
 
 func generatePublicKey(privateKey *rsa.PrivateKey) *rsa.PublicKey {
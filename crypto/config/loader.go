@@ -9,52 +9,92 @@ import (
 	"github.com/nected/go-lib/crypto/models"
 )
 
+// KeyVarError reports an environment variable whose key could not be loaded.
+type KeyVarError struct {
+	Name string
+	Err  error
+}
+
+func (e *KeyVarError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Name, e.Err)
+}
+
+func (e *KeyVarError) Unwrap() error {
+	return e.Err
+}
+
+// KeyEnvError aggregates the errors of every environment variable with the
+// given prefix whose key could not be loaded. Keys from the other variables
+// are still registered.
+type KeyEnvError struct {
+	Prefix string
+	Vars   []*KeyVarError
+}
+
+func (e *KeyEnvError) Error() string {
+	msgs := make([]string, 0, len(e.Vars))
+	for _, varErr := range e.Vars {
+		msgs = append(msgs, varErr.Error())
+	}
+	return fmt.Sprintf("failed to load %d %s variable(s): %s", len(e.Vars), e.Prefix, strings.Join(msgs, "; "))
+}
+
+func (e *KeyEnvError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Vars))
+	for _, varErr := range e.Vars {
+		errs = append(errs, varErr)
+	}
+	return errs
+}
+
 // key config format
 func LoadKeysFromFile(keyName, keyPath string) error {
+	return LoadKeysFromFileToStore(models.DefaultKeyStore(), keyName, keyPath)
+}
+
+// LoadKeysFromFileToStore loads the key at keyPath into store as version 1 of
+// keyName. The file holds a PEM encoded private key, public key or X.509
+// certificate; public keys and certificates only encrypt and verify. See
+// LoadKeysFromEnv for the supported formats.
+func LoadKeysFromFileToStore(store models.KeyStore, keyName, keyPath string) error {
 	version := 1
-	info := models.GetEncryptKeysMap()
-	if info == nil {
-		info = &models.EncryptStruct{
-			AvailableKeys: make(map[string]map[int]models.KeyInfo),
-		}
-		models.SetEncryptKeysMap(info)
-	}
-	privateKey, err := loadPrivateKeyFromFile(keyPath)
+	keyInfo, err := loadKeyFromFile(keyPath, keyPassphrase(keyName, version))
 	if err != nil {
 		return err
 	}
-	publicKey := generatePublicKey(privateKey)
-	keyInfo := models.KeyInfo{
-		PrivKey: privateKey,
-		PubKey:  publicKey,
-		Name:    keyName,
-		Version: version,
-	}
+	keyInfo.SetName(keyName)
+	keyInfo.SetVersion(version)
 
-	info.AvailableKeys[keyName][keyInfo.Version] = keyInfo
-	return nil
+	return store.Put(keyInfo)
 }
 
 // env key format
 //
-// ENCRYPTKEY_<key_name>_<key_version>
+// ENCRYPTKEY_<key_name>_<key_version>_<key_state>
 //
 // Parameters:
 //   - key_name: name of the key
 //   - key_version(optional): version of the key, default is 1
+//   - key_state(optional): ACTIVE, DECRYPTONLY or REVOKED, default is ACTIVE
+//
+// The value is a PEM encoded RSA, ECDSA, Ed25519 or X25519 private key, or a
+// PUBLIC KEY, RSA PUBLIC KEY or CERTIFICATE block for keys that only encrypt
+// and verify. The passphrase of an encrypted private key is read from
+// ENCRYPTPASS_<key_name>_<key_version> unless SetPassphraseFunc is used.
 //
 // Example:
 //   - ENCRYPTKEY_TESTKEY_1
-func LoadKeysFromEnv() (err error) {
-	info := models.GetEncryptKeysMap()
-
-	if info == nil {
-		info = &models.EncryptStruct{
-			AvailableKeys: make(map[string]map[int]models.KeyInfo),
-		}
-		models.SetEncryptKeysMap(info)
-	}
+//   - ENCRYPTKEY_TESTKEY_1_DECRYPTONLY
+func LoadKeysFromEnv() error {
+	return LoadKeysFromEnvToStore(models.DefaultKeyStore())
+}
 
+// LoadKeysFromEnvToStore loads every ENCRYPTKEY_ variable into store.
+// See LoadKeysFromEnv for the variable format. Variables holding a key that
+// can not be loaded are reported in a *KeyEnvError once the other keys are
+// registered.
+func LoadKeysFromEnvToStore(store models.KeyStore) error {
+	envErr := &KeyEnvError{Prefix: KEY_ENV_PREFIX}
 	for _, env := range os.Environ() {
 		if !strings.HasPrefix(env, KEY_ENV_PREFIX) {
 			continue
@@ -74,50 +114,63 @@ func LoadKeysFromEnv() (err error) {
 			// key not found
 			continue
 		}
-		parts := strings.Split(key, "_")
-		if len(parts) < 2 {
-			// invalid key format
-			// key name is missing
+		keyName, keyVersion, keyState, ok := parseEnvKeyName(key)
+		if !ok {
+			// invalid key name, version or state
 			continue
 		}
 
-		keyName := parts[1]
-
-		keyVersion := 1
-		if len(parts) >= 3 {
-			keyVersionStr := parts[2]
-			if keyVersionStr != "" {
-				// convert to int
-				keyVersion, err = strconv.Atoi(keyVersionStr)
-				if err != nil {
-					// invalid key version
-					continue
-				}
-			}
-		}
-
-		privateKey, err := loadPrivateKey([]byte(value))
+		keyInfo, err := loadKey([]byte(value), keyPassphrase(keyName, keyVersion))
 		if err != nil {
-			fmt.Println("Invalid private key")
+			envErr.Vars = append(envErr.Vars, &KeyVarError{Name: key, Err: err})
 			continue
 		}
+		keyInfo.SetName(keyName)
+		keyInfo.SetVersion(keyVersion)
+		keyInfo.SetState(keyState)
 
-		if err := privateKey.Validate(); err != nil {
-			fmt.Println("Invalid private key")
-			continue
+		if err := store.Put(keyInfo); err != nil {
+			envErr.Vars = append(envErr.Vars, &KeyVarError{Name: key, Err: err})
 		}
+	}
 
-		if _, ok := info.AvailableKeys[keyName]; !ok {
-			info.AvailableKeys[keyName] = make(map[int]models.KeyInfo)
-		}
+	if len(envErr.Vars) > 0 {
+		return envErr
+	}
+	return nil
+}
+
+// parseEnvKeyName parses the name, version and state out of a variable name
+// of the form <PREFIX>_<key_name>_<key_version>_<key_state>.
+func parseEnvKeyName(key string) (string, int, models.KeyState, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) < 2 {
+		// key name is missing
+		return "", 0, models.KeyStateActive, false
+	}
+
+	keyName := parts[1]
 
-		info.AvailableKeys[keyName][keyVersion] = models.KeyInfo{
-			Name:    keyName,
-			Version: keyVersion,
-			PrivKey: privateKey,
-			PubKey:  generatePublicKey(privateKey),
+	keyVersion := 1
+	if len(parts) >= 3 {
+		keyVersionStr := parts[2]
+		if keyVersionStr != "" {
+			// convert to int
+			var err error
+			keyVersion, err = strconv.Atoi(keyVersionStr)
+			if err != nil {
+				return "", 0, models.KeyStateActive, false
+			}
 		}
+	}
 
+	keyState := models.KeyStateActive
+	if len(parts) >= 4 {
+		var err error
+		keyState, err = models.ParseKeyState(parts[3])
+		if err != nil {
+			return "", 0, models.KeyStateActive, false
+		}
 	}
-	return nil
+	return keyName, keyVersion, keyState, true
 }
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// PassphraseFunc returns the passphrase of the encrypted private key loaded
+// as the given version of keyName.
+type PassphraseFunc func(keyName string, keyVersion int) ([]byte, error)
+
+var passphraseFunc atomic.Pointer[PassphraseFunc]
+
+// SetPassphraseFunc sets the source of the passphrases of encrypted private
+// keys, e.g. to read them from a secret manager. A nil fn restores the
+// default, PassphraseFromEnv.
+func SetPassphraseFunc(fn PassphraseFunc) {
+	if fn == nil {
+		passphraseFunc.Store(nil)
+		return
+	}
+	passphraseFunc.Store(&fn)
+}
+
+// env passphrase format
+//
+// ENCRYPTPASS_<key_name>_<key_version>
+//
+// Example:
+//   - ENCRYPTPASS_TESTKEY_1
+func PassphraseFromEnv(keyName string, keyVersion int) ([]byte, error) {
+	passphrase, ok := os.LookupEnv(fmt.Sprintf("%s_%s_%d", PASS_ENV_PREFIX, keyName, keyVersion))
+	if !ok {
+		return nil, fmt.Errorf("no passphrase for encrypted key %s version %d", keyName, keyVersion)
+	}
+	return []byte(passphrase), nil
+}
+
+// keyPassphrase defers the passphrase lookup of a key until loadKey finds
+// that it is encrypted.
+func keyPassphrase(keyName string, keyVersion int) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		if fn := passphraseFunc.Load(); fn != nil {
+			return (*fn)(keyName, keyVersion)
+		}
+		return PassphraseFromEnv(keyName, keyVersion)
+	}
+}
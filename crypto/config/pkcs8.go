@@ -0,0 +1,159 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Encrypted PKCS#8 keys, as written by `openssl pkcs8 -topk8 -v2 aes256`,
+// are decrypted here because crypto/x509 does not support them. Only PBES2
+// with PBKDF2 and AES-CBC, the scheme used by every current OpenSSL release,
+// is supported (RFC 8018).
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// pbkdf2MaxIterations bounds the work a forged key file can make us do.
+const pbkdf2MaxIterations = 10_000_000
+
+type encryptedPrivateKeyInfo struct {
+	EncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 decrypts the DER encoded EncryptedPrivateKeyInfo in der and
+// returns the DER encoded PKCS#8 private key.
+func decryptPKCS8(der, password []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if err := unmarshalDER(der, &info); err != nil {
+		return nil, err
+	}
+	if !info.EncryptionAlgorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported PKCS#8 encryption %v", info.EncryptionAlgorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if err := unmarshalDER(info.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported PKCS#8 key derivation %v", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdfParams pbkdf2Params
+	if err := unmarshalDER(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, err
+	}
+	if kdfParams.IterationCount < 1 || kdfParams.IterationCount > pbkdf2MaxIterations {
+		return nil, fmt.Errorf("unsupported PBKDF2 iteration count %d", kdfParams.IterationCount)
+	}
+	prf, err := pbkdf2PRF(kdfParams.PRF)
+	if err != nil {
+		return nil, err
+	}
+
+	keySize, err := aesCBCKeySize(params.EncryptionScheme.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if kdfParams.KeyLength != 0 && kdfParams.KeyLength != keySize {
+		return nil, fmt.Errorf("invalid PBKDF2 key length %d", kdfParams.KeyLength)
+	}
+	var iv []byte
+	if err := unmarshalDER(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid AES-CBC IV length %d", len(iv))
+	}
+	encryptedData := info.EncryptedData
+	if len(encryptedData) == 0 || len(encryptedData)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid PKCS#8 encrypted data length %d", len(encryptedData))
+	}
+
+	key := pbkdf2.Key(password, kdfParams.Salt, kdfParams.IterationCount, keySize, prf)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	decryptedData := make([]byte, len(encryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decryptedData, encryptedData)
+
+	// a wrong password almost always shows up as invalid padding
+	padding := int(decryptedData[len(decryptedData)-1])
+	if padding == 0 || padding > aes.BlockSize ||
+		!bytes.Equal(decryptedData[len(decryptedData)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("incorrect passphrase")
+	}
+	return decryptedData[:len(decryptedData)-padding], nil
+}
+
+func pbkdf2PRF(prf pkix.AlgorithmIdentifier) (func() hash.Hash, error) {
+	switch {
+	// hmacWithSHA1 is the default when the PRF is omitted
+	case len(prf.Algorithm) == 0, prf.Algorithm.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case prf.Algorithm.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case prf.Algorithm.Equal(oidHMACWithSHA384):
+		return sha512.New384, nil
+	case prf.Algorithm.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported PBKDF2 PRF %v", prf.Algorithm)
+}
+
+func aesCBCKeySize(scheme asn1.ObjectIdentifier) (int, error) {
+	switch {
+	case scheme.Equal(oidAES128CBC):
+		return 16, nil
+	case scheme.Equal(oidAES192CBC):
+		return 24, nil
+	case scheme.Equal(oidAES256CBC):
+		return 32, nil
+	}
+	return 0, fmt.Errorf("unsupported PKCS#8 cipher %v", scheme)
+}
+
+// unmarshalDER unmarshals der into v and rejects trailing data.
+func unmarshalDER(der []byte, v any) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return fmt.Errorf("invalid encrypted private key: %w", err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("invalid encrypted private key: trailing data")
+	}
+	return nil
+}
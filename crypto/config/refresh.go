@@ -0,0 +1,158 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// defaultMinBackoff is the first retry delay of a Refresher after a failure.
+const defaultMinBackoff = time.Second
+
+// Refresher periodically reloads the keys of a KeySource into a store.
+//
+// Every load builds a fresh key set which replaces the keys of the store in a
+// single step, like Reload, so keys removed from the source are dropped while
+// the state of the remaining key versions is kept.
+// After a failed load the store keeps its keys and the load is retried after
+// MinBackoff, doubling the delay on each further failure up to MaxBackoff.
+//
+// Example:
+//
+//	refresher := &config.Refresher{
+//		Source:   &config.HTTPSource{URL: "https://secrets.internal/keys"},
+//		Interval: 5 * time.Minute,
+//	}
+//	go func() {
+//		if err := refresher.Run(ctx); err != nil && err != context.Canceled {
+//			log.Print(err)
+//		}
+//	}()
+type Refresher struct {
+	// Source of the keys.
+	Source KeySource
+	// Store receives the keys. The default store is used when nil.
+	Store models.ReplaceableKeyStore
+	// Interval between successful loads. It must be positive.
+	Interval time.Duration
+	// MinBackoff is the delay before retrying a failed load, one second when
+	// zero.
+	MinBackoff time.Duration
+	// MaxBackoff caps the retry delay, Interval when zero. It is never below
+	// MinBackoff.
+	MaxBackoff time.Duration
+	// OnError, when set, is called with the error of every failed load.
+	OnError func(error)
+
+	mu    sync.Mutex
+	stats RefreshStats
+}
+
+// RefreshStats describes the loads done by a Refresher.
+type RefreshStats struct {
+	// LastAttempt is when the last load started.
+	LastAttempt time.Time
+	// LastSuccess is when the last successful load finished.
+	LastSuccess time.Time
+	// LastError is the error of the last load, nil if it succeeded.
+	LastError error
+	// KeyCount is the number of keys of the last successful load.
+	KeyCount int
+	// Successes and Failures count the loads since the Refresher was created.
+	Successes int
+	Failures  int
+	// ConsecutiveFailures counts the failures since the last success.
+	ConsecutiveFailures int
+}
+
+// Run loads the keys and then reloads them every Interval, or sooner after a
+// failure. It blocks until ctx is done and then returns the error of ctx, so
+// it is usually run in its own goroutine. Run returns an error right away when
+// Interval is not positive.
+func (r *Refresher) Run(ctx context.Context) error {
+	if r.Interval <= 0 {
+		return fmt.Errorf("refresh interval %v is not positive", r.Interval)
+	}
+	for {
+		delay := r.Interval
+		if err := r.Refresh(ctx); err != nil {
+			delay = r.backoff()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Refresh loads the keys once and swaps them into the store.
+func (r *Refresher) Refresh(ctx context.Context) error {
+	started := time.Now()
+	next := models.NewMemoryKeyStore()
+	err := r.Source.Load(ctx, next)
+	keys := next.List()
+	if err == nil {
+		if r.Store != nil {
+			err = r.Store.Replace(keepStates(r.Store, keys))
+		} else {
+			err = replaceDefaultKeys(keys)
+		}
+	}
+
+	r.mu.Lock()
+	r.stats.LastAttempt = started
+	r.stats.LastError = err
+	if err != nil {
+		r.stats.Failures++
+		r.stats.ConsecutiveFailures++
+	} else {
+		r.stats.LastSuccess = time.Now()
+		r.stats.KeyCount = len(keys)
+		r.stats.Successes++
+		r.stats.ConsecutiveFailures = 0
+	}
+	r.mu.Unlock()
+
+	if err != nil && r.OnError != nil {
+		r.OnError(err)
+	}
+	return err
+}
+
+// Stats returns a snapshot of the loads done so far.
+func (r *Refresher) Stats() RefreshStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// backoff returns the delay before retrying after the current run of
+// consecutive failures.
+func (r *Refresher) backoff() time.Duration {
+	minBackoff := r.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinBackoff
+	}
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = r.Interval
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
+
+	delay := minBackoff
+	for failures := r.Stats().ConsecutiveFailures; failures > 1 && delay < maxBackoff; failures-- {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
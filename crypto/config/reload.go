@@ -0,0 +1,61 @@
+package config
+
+import (
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// Reload builds a fresh key set with load and swaps it into store in a single
+// step, so decrypts running concurrently see either the old or the new keys
+// but never a partially loaded set. store is left untouched when load fails.
+// Key versions revoked or made decrypt-only in store keep their state.
+//
+// Example:
+//
+//	err := config.Reload(store, config.LoadKeysFromEnvToStore)
+func Reload(store models.ReplaceableKeyStore, load func(models.KeyStore) error) error {
+	next := models.NewMemoryKeyStore()
+	if err := load(next); err != nil {
+		return err
+	}
+	return store.Replace(keepStates(store, next.List()))
+}
+
+// ReloadKeysFromEnv replaces the keys of the default store with the ones
+// currently set in the environment. The default store keeps its keys when a
+// variable fails to load, and key versions keep their state like with Reload.
+func ReloadKeysFromEnv() error {
+	next := models.NewMemoryKeyStore()
+	if err := LoadKeysFromEnvToStore(next); err != nil {
+		return err
+	}
+	return replaceDefaultKeys(next.List())
+}
+
+// replaceDefaultKeys swaps keys into the default store, or replaces the
+// default store when it can not swap its keys.
+func replaceDefaultKeys(keys []models.KeyInfo) error {
+	keys = keepStates(models.DefaultKeyStore(), keys)
+	if store, ok := models.DefaultKeyStore().(models.ReplaceableKeyStore); ok {
+		return store.Replace(keys)
+	}
+	next := models.NewMemoryKeyStore()
+	if err := next.Replace(keys); err != nil {
+		return err
+	}
+	models.SetDefaultKeyStore(next)
+	return nil
+}
+
+// keepStates carries the state of the key versions of store over to keys, so
+// a reload does not bring back key versions revoked or made decrypt-only with
+// models.SetKeyState. A loaded state that restricts a key further wins: the
+// states are ordered from active to revoked.
+func keepStates(store models.KeyStore, keys []models.KeyInfo) []models.KeyInfo {
+	for i := range keys {
+		current := store.Get(keys[i].GetName(), keys[i].GetVersion())
+		if current != nil && current.GetState() > keys[i].GetState() {
+			keys[i].SetState(current.GetState())
+		}
+	}
+	return keys
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// TestReloadKeepsKeyStates checks that reloads do not bring back key versions
+// revoked or made decrypt-only since the last load.
+func TestReloadKeepsKeyStates(t *testing.T) {
+	load := func(store models.KeyStore) error {
+		for version := 1; version <= 3; version++ {
+			err := store.Put(models.KeyInfo{Name: "STATEKEY", Version: version, Secret: bytes.Repeat([]byte{byte(version)}, 32)})
+			if err != nil {
+				return err
+			}
+		}
+		// the source itself marks version 3 as decrypt only
+		return models.SetKeyState(store, "STATEKEY", 3, models.KeyStateDecryptOnly)
+	}
+	source := KeySourceFunc(func(_ context.Context, store models.KeyStore) error {
+		return load(store)
+	})
+
+	tests := []struct {
+		name   string
+		reload func(store models.ReplaceableKeyStore) error
+	}{
+		{"Reload", func(store models.ReplaceableKeyStore) error {
+			return Reload(store, load)
+		}},
+		{"Refresher", func(store models.ReplaceableKeyStore) error {
+			return (&Refresher{Source: source, Store: store}).Refresh(context.Background())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := models.NewMemoryKeyStore()
+			if err := tt.reload(store); err != nil {
+				t.Fatalf("reload error = %v", err)
+			}
+			if err := models.SetKeyState(store, "STATEKEY", 1, models.KeyStateRevoked); err != nil {
+				t.Fatalf("SetKeyState() error = %v", err)
+			}
+			if err := models.SetKeyState(store, "STATEKEY", 2, models.KeyStateDecryptOnly); err != nil {
+				t.Fatalf("SetKeyState() error = %v", err)
+			}
+			if err := models.SetKeyState(store, "STATEKEY", 3, models.KeyStateActive); err != nil {
+				t.Fatalf("SetKeyState() error = %v", err)
+			}
+			if err := tt.reload(store); err != nil {
+				t.Fatalf("reload error = %v", err)
+			}
+
+			for version, want := range map[int]models.KeyState{
+				1: models.KeyStateRevoked,
+				2: models.KeyStateDecryptOnly,
+				3: models.KeyStateDecryptOnly,
+			} {
+				keyInfo := store.Get("STATEKEY", version)
+				if keyInfo == nil || keyInfo.GetState() != want {
+					t.Errorf("Get(%d) = %v, want %v", version, keyInfo, want)
+				}
+			}
+		})
+	}
+}
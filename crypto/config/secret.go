@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// env secret format
+//
+// ENCRYPTSECRET_<key_name>_<key_version>_<key_state>=<base64 secret>
+//
+// Parameters:
+//   - key_name: name of the secret
+//   - key_version(optional): version of the secret, default is 1
+//   - key_state(optional): ACTIVE, DECRYPTONLY or REVOKED, default is ACTIVE
+//
+// The value is the standard base64 encoding of a 16, 24 or 32 byte AES key,
+// e.g. the output of `openssl rand -base64 32`.
+//
+// Example:
+//   - ENCRYPTSECRET_TESTSECRET_1
+//   - ENCRYPTSECRET_TESTSECRET_2_DECRYPTONLY
+func LoadSecretsFromEnv() error {
+	return LoadSecretsFromEnvToStore(models.DefaultSecretStore())
+}
+
+// LoadSecretsFromEnvToStore loads every ENCRYPTSECRET_ variable into store.
+// See LoadSecretsFromEnv for the variable format. Variables holding a secret
+// that can not be loaded are reported in a *KeyEnvError once the other
+// secrets are registered.
+func LoadSecretsFromEnvToStore(store models.KeyStore) error {
+	envErr := &KeyEnvError{Prefix: SECRET_ENV_PREFIX}
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, SECRET_ENV_PREFIX+"_") {
+			continue
+		}
+		key, value, _ := strings.Cut(env, "=")
+
+		keyName, keyVersion, keyState, ok := parseEnvKeyName(key)
+		if !ok {
+			// invalid key name, version or state
+			continue
+		}
+
+		secret, err := decodeSecret(value)
+		if err != nil {
+			envErr.Vars = append(envErr.Vars, &KeyVarError{Name: key, Err: err})
+			continue
+		}
+
+		err = store.Put(models.KeyInfo{
+			Name:    keyName,
+			Version: keyVersion,
+			State:   keyState,
+			Secret:  secret,
+		})
+		if err != nil {
+			envErr.Vars = append(envErr.Vars, &KeyVarError{Name: key, Err: err})
+		}
+	}
+
+	if len(envErr.Vars) > 0 {
+		return envErr
+	}
+	return nil
+}
+
+// LoadSecretFromFile loads the base64 encoded secret at secretPath into the
+// default secret store as version 1 of keyName.
+func LoadSecretFromFile(keyName, secretPath string) error {
+	return LoadSecretFromFileToStore(models.DefaultSecretStore(), keyName, secretPath)
+}
+
+// LoadSecretFromFileToStore behaves like LoadSecretFromFile but loads the
+// secret into store.
+func LoadSecretFromFileToStore(store models.KeyStore, keyName, secretPath string) error {
+	fileData, err := os.ReadFile(secretPath)
+	if err != nil {
+		return err
+	}
+	secret, err := decodeSecret(string(fileData))
+	if err != nil {
+		return err
+	}
+	return store.Put(models.KeyInfo{
+		Name:    keyName,
+		Version: 1,
+		Secret:  secret,
+	})
+}
+
+func decodeSecret(value string) ([]byte, error) {
+	secret, err := base64.B64Decode(strings.TrimSpace(value))
+	if err != nil {
+		return nil, err
+	}
+	switch len(secret) {
+	case 16, 24, 32:
+		return []byte(secret), nil
+	}
+	return nil, fmt.Errorf("invalid secret length %d, want 16, 24 or 32 bytes", len(secret))
+}
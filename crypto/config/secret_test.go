@@ -0,0 +1,59 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func TestLoadSecretsFromEnvToStore(t *testing.T) {
+	secret := bytes.Repeat([]byte{7}, 32)
+	encoded := base64.StdEncoding.EncodeToString(secret)
+	t.Setenv(SECRET_ENV_PREFIX+"_ENVSECRET_1", encoded)
+	t.Setenv(SECRET_ENV_PREFIX+"_ENVSECRET_2_DECRYPTONLY", encoded)
+	t.Setenv(SECRET_ENV_PREFIX+"_SHORTSECRET_1", base64.StdEncoding.EncodeToString([]byte("short")))
+	t.Setenv(SECRET_ENV_PREFIX+"_RAWSECRET_1", "someRandomSecret")
+
+	store := models.NewMemoryKeyStore()
+	err := LoadSecretsFromEnvToStore(store)
+	var envErr *KeyEnvError
+	if !errors.As(err, &envErr) {
+		t.Fatalf("LoadSecretsFromEnvToStore() error = %v, want a *KeyEnvError", err)
+	}
+	if len(envErr.Vars) != 2 {
+		t.Errorf("LoadSecretsFromEnvToStore() reported %v variables, want 2", len(envErr.Vars))
+	}
+
+	keyInfo := store.Get("ENVSECRET", 1)
+	if keyInfo == nil || !bytes.Equal(keyInfo.GetSecret(), secret) {
+		t.Fatalf("Get() = %v, want the decoded secret", keyInfo)
+	}
+	if keyInfo := store.Get("ENVSECRET", 2); keyInfo == nil || keyInfo.GetState() != models.KeyStateDecryptOnly {
+		t.Errorf("Get() = %v, want a %v secret", keyInfo, models.KeyStateDecryptOnly)
+	}
+	if got := len(store.List()); got != 2 {
+		t.Errorf("List() = %v secrets, want 2", got)
+	}
+}
+
+func TestLoadSecretFromFileToStore(t *testing.T) {
+	dir := t.TempDir()
+	secret := bytes.Repeat([]byte{9}, 16)
+	writeKeyFile(t, dir, "secret.key", base64.StdEncoding.EncodeToString(secret)+"\n")
+	writeKeyFile(t, dir, "broken.key", "not base64!")
+
+	store := models.NewMemoryKeyStore()
+	if err := LoadSecretFromFileToStore(store, "FILESECRET", filepath.Join(dir, "secret.key")); err != nil {
+		t.Fatalf("LoadSecretFromFileToStore() error = %v", err)
+	}
+	if keyInfo := store.Get("FILESECRET", 1); keyInfo == nil || !bytes.Equal(keyInfo.GetSecret(), secret) {
+		t.Errorf("Get() = %v, want the decoded secret", keyInfo)
+	}
+	if err := LoadSecretFromFileToStore(store, "BROKEN", filepath.Join(dir, "broken.key")); err == nil {
+		t.Errorf("LoadSecretFromFileToStore() error = nil, want an error")
+	}
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func newKeySetServer(t *testing.T, keySet any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(keySet)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPSource(t *testing.T) {
+	privateKey := generatePrivateKey()
+	server := newKeySetServer(t, map[string]any{
+		"keys": []map[string]any{
+			{"name": "HTTPKEY", "version": 1, "state": "DECRYPTONLY", "pem": privateKey},
+			{"name": "HTTPKEY", "version": 2, "pem": privateKey},
+			{"name": "OTHERKEY", "pem": privateKey},
+		},
+	})
+	header := http.Header{"Authorization": []string{"Bearer token"}}
+
+	store := models.NewMemoryKeyStore()
+	source := &HTTPSource{URL: server.URL, Header: header}
+	if err := source.Load(context.Background(), store); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if keyInfo := store.Get("HTTPKEY", 1); keyInfo == nil || keyInfo.GetState() != models.KeyStateDecryptOnly {
+		t.Errorf("Get() = %v, want HTTPKEY version 1 decrypt-only", keyInfo)
+	}
+	if latest := store.Latest("HTTPKEY"); latest == nil || latest.GetVersion() != 2 {
+		t.Errorf("Latest() = %v, want HTTPKEY version 2", latest)
+	}
+	if store.Get("OTHERKEY", 1) == nil {
+		t.Errorf("Get() = nil, want OTHERKEY to default to version 1")
+	}
+
+	invalidKeyServer := newKeySetServer(t, map[string]any{
+		"keys": []map[string]any{{"name": "BADKEY", "pem": "not a key"}},
+	})
+	invalidJSONServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{"))
+	}))
+	defer invalidJSONServer.Close()
+
+	tests := []struct {
+		name   string
+		source *HTTPSource
+	}{
+		{"Unauthorized", &HTTPSource{URL: server.URL}},
+		{"Invalid key", &HTTPSource{URL: invalidKeyServer.URL, Header: header}},
+		{"Invalid JSON", &HTTPSource{URL: invalidJSONServer.URL}},
+		{"Invalid URL", &HTTPSource{URL: "://"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.source.Load(context.Background(), models.NewMemoryKeyStore()); err == nil {
+				t.Errorf("Load() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestMultiSource(t *testing.T) {
+	dir := t.TempDir()
+	writeKeyFile(t, dir, "DIRKEY_1.pem", generatePrivateKey())
+	t.Setenv(KEY_ENV_PREFIX+"_ENVKEY_1", generatePrivateKey())
+
+	store := models.NewMemoryKeyStore()
+	if err := MultiSource(EnvSource(), DirSource(dir)).Load(context.Background(), store); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if store.Get("ENVKEY", 1) == nil || store.Get("DIRKEY", 1) == nil {
+		t.Errorf("List() = %v, want ENVKEY and DIRKEY", store.List())
+	}
+
+	failing := KeySourceFunc(func(context.Context, models.KeyStore) error {
+		return errors.New("unavailable")
+	})
+	if err := MultiSource(EnvSource(), failing).Load(context.Background(), models.NewMemoryKeyStore()); err == nil {
+		t.Errorf("Load() error = nil, want the error of the failing source")
+	}
+}
+
+func TestRefresher(t *testing.T) {
+	privateKey := generatePrivateKey()
+	keyInfo, err := loadKey([]byte(privateKey), nil)
+	if err != nil {
+		t.Fatalf("loadKey() error = %v", err)
+	}
+
+	// the source fails twice, then returns one more version on every load
+	var loads atomic.Int32
+	source := KeySourceFunc(func(_ context.Context, store models.KeyStore) error {
+		n := int(loads.Add(1))
+		if n <= 2 {
+			return errors.New("unavailable")
+		}
+		keyInfo.SetName("REFRESHKEY")
+		keyInfo.SetVersion(n - 2)
+		return store.Put(keyInfo)
+	})
+
+	store := models.NewMemoryKeyStore()
+	var errCount atomic.Int32
+	refresher := &Refresher{
+		Source:     source,
+		Store:      store,
+		Interval:   10 * time.Millisecond,
+		MinBackoff: time.Millisecond,
+		OnError:    func(error) { errCount.Add(1) },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- refresher.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for refresher.Stats().Successes < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Stats() = %+v, want 2 successful loads", refresher.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-stopped; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+
+	stats := refresher.Stats()
+	if stats.Failures != 2 || errCount.Load() != 2 {
+		t.Errorf("Stats().Failures = %v, OnError calls = %v, want 2", stats.Failures, errCount.Load())
+	}
+	if stats.ConsecutiveFailures != 0 || stats.LastError != nil || stats.LastSuccess.IsZero() || stats.KeyCount != 1 {
+		t.Errorf("Stats() = %+v, want a successful last load of 1 key", stats)
+	}
+	// every load replaces the keys of the previous one
+	if keys := store.List(); len(keys) != 1 || keys[0].GetVersion() < 2 {
+		t.Errorf("List() = %v, want only the latest loaded version", keys)
+	}
+
+	// a failed load keeps the keys
+	failing := &Refresher{Source: KeySourceFunc(func(context.Context, models.KeyStore) error {
+		return errors.New("unavailable")
+	}), Store: store, Interval: time.Minute}
+	if err := failing.Refresh(context.Background()); err == nil {
+		t.Errorf("Refresh() error = nil, want an error")
+	}
+	if len(store.List()) != 1 {
+		t.Errorf("List() = %v, want the keys of the last successful load", store.List())
+	}
+}
+
+func TestRefresherBackoff(t *testing.T) {
+	refresher := &Refresher{Interval: time.Minute, MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		refresher.stats.ConsecutiveFailures++
+		if got := refresher.backoff(); got != want {
+			t.Errorf("backoff() after %d failures = %v, want %v", refresher.stats.ConsecutiveFailures, got, want)
+		}
+	}
+
+	// the retry delay never exceeds the interval by default
+	refresher = &Refresher{Interval: 3 * time.Second}
+	refresher.stats.ConsecutiveFailures = 10
+	if got := refresher.backoff(); got != 3*time.Second {
+		t.Errorf("backoff() = %v, want %v", got, 3*time.Second)
+	}
+
+	// nor goes below the first retry delay
+	refresher = &Refresher{Interval: time.Millisecond}
+	refresher.stats.ConsecutiveFailures = 1
+	if got := refresher.backoff(); got != defaultMinBackoff {
+		t.Errorf("backoff() = %v, want %v", got, defaultMinBackoff)
+	}
+}
+
+func TestRefresherInvalidInterval(t *testing.T) {
+	var loads atomic.Int32
+	source := KeySourceFunc(func(context.Context, models.KeyStore) error {
+		loads.Add(1)
+		return nil
+	})
+	for _, interval := range []time.Duration{0, -time.Second} {
+		refresher := &Refresher{Source: source, Store: models.NewMemoryKeyStore(), Interval: interval}
+		if err := refresher.Run(context.Background()); err == nil {
+			t.Errorf("Run() with interval %v error = nil, want an error", interval)
+		}
+	}
+	if loads.Load() != 0 {
+		t.Errorf("Source loaded %v times, want 0", loads.Load())
+	}
+}
@@ -1,10 +1,18 @@
 package crypto
 
 import (
+	"context"
+	"io"
+
 	"github.com/nected/go-lib/crypto/aes"
+	"github.com/nected/go-lib/crypto/blindindex"
 	"github.com/nected/go-lib/crypto/config"
+	"github.com/nected/go-lib/crypto/fpe"
+	"github.com/nected/go-lib/crypto/jwk"
+	"github.com/nected/go-lib/crypto/jwt"
 	"github.com/nected/go-lib/crypto/models"
 	"github.com/nected/go-lib/crypto/rsa"
+	"github.com/nected/go-lib/crypto/sign"
 )
 
 func EncryptRSA(keyName string, data []byte) (*models.Payload, error) {
@@ -15,6 +23,104 @@ func DecryptRSA(data string) (*models.Payload, error) {
 	return rsa.Decrypt(data)
 }
 
+func EncryptRSAWithAAD(keyName string, data, additionalData []byte) (*models.Payload, error) {
+	return rsa.EncryptWithAAD(keyName, data, additionalData)
+}
+
+func DecryptRSAWithAAD(data string, additionalData []byte) (*models.Payload, error) {
+	return rsa.DecryptWithAAD(data, additionalData)
+}
+
+func EncryptRSAEnvelope(keyName string, data []byte) (*models.Payload, error) {
+	return rsa.EncryptEnvelope(keyName, data)
+}
+
+func EncryptRSAWithStore(store models.KeyStore, keyName string, data []byte) (*models.Payload, error) {
+	return rsa.EncryptWithStore(store, keyName, data)
+}
+
+func DecryptRSAWithStore(store models.KeyStore, data string) (*models.Payload, error) {
+	return rsa.DecryptWithStore(store, data)
+}
+
+// EncryptBatch encrypts items with the named RSA key on a bounded pool of
+// workers. Results keep the order of items and carry per-item errors.
+func EncryptBatch(ctx context.Context, keyName string, items [][]byte, opts ...rsa.BatchOption) ([]rsa.BatchResult, error) {
+	return rsa.EncryptBatch(ctx, keyName, items, opts...)
+}
+
+func DecryptBatch(ctx context.Context, items []string, opts ...rsa.BatchOption) ([]rsa.BatchResult, error) {
+	return rsa.DecryptBatch(ctx, items, opts...)
+}
+
+func DecryptRSAHardened(data string) (*models.Payload, error) {
+	return rsa.DecryptHardened(data)
+}
+
+// Decrypt decrypts a serialized RSA or named AES payload, picking the
+// algorithm from the payload header. Plain text is returned as is.
+func Decrypt(data string) (*models.Payload, error) {
+	if parsed := models.ParsePayload(data); parsed != nil && aes.IsNamedKeyType(parsed.KeyType) {
+		return aes.DecryptNamed(data)
+	}
+	return rsa.Decrypt(data)
+}
+
+// DecryptStrict behaves like Decrypt but fails instead of returning its input
+// unchanged: with errors.ErrMalformedHeader for plain text and with a
+// *errors.KeyError when the key version is not loaded.
+func DecryptStrict(data string) (*models.Payload, error) {
+	if parsed := models.ParsePayload(data); parsed != nil && aes.IsNamedKeyType(parsed.KeyType) {
+		return aes.DecryptNamed(data)
+	}
+	return rsa.DecryptStrict(data)
+}
+
+func Rotate(ciphertext string) (string, bool, error) {
+	if parsed := models.ParsePayload(ciphertext); parsed != nil && aes.IsNamedKeyType(parsed.KeyType) {
+		return aes.Rotate(ciphertext)
+	}
+	return rsa.Rotate(ciphertext)
+}
+
+// RotateDocument behaves like rsa.RotateDocument but rotates every value like
+// Rotate, so values encrypted with named secrets are rotated too.
+func RotateDocument(doc map[string]any) (int, error) {
+	return rsa.RotateDocumentFunc(doc, Rotate)
+}
+
+func BlindIndex(keyName, value string, opts ...blindindex.Option) (string, error) {
+	return blindindex.Token(keyName, value, opts...)
+}
+
+func EncryptFPE(keyName, value string, domain fpe.Domain, opts ...fpe.Option) (string, int, error) {
+	return fpe.Encrypt(keyName, value, domain, opts...)
+}
+
+func DecryptFPE(keyName string, keyVersion int, ciphertext string, domain fpe.Domain, opts ...fpe.Option) (string, error) {
+	return fpe.Decrypt(keyName, keyVersion, ciphertext, domain, opts...)
+}
+
+func Sign(keyName string, data []byte) (string, error) {
+	return sign.Sign(keyName, data)
+}
+
+func SignWithAlgorithm(keyName string, data []byte, algorithm models.KeyType) (string, error) {
+	return sign.SignWithAlgorithm(keyName, data, algorithm)
+}
+
+func Verify(signature string, data []byte) error {
+	return sign.Verify(signature, data)
+}
+
+func IssueJWT(keyName string, claims jwt.Claims, opts ...jwt.Option) (string, error) {
+	return jwt.Issue(keyName, claims, opts...)
+}
+
+func ParseJWT(token string, claims jwt.Claims, opts ...jwt.Option) error {
+	return jwt.Parse(token, claims, opts...)
+}
+
 func EncryptAES(secret string, data []byte) (*models.Payload, error) {
 	return aes.Encrypt(secret, data)
 }
@@ -23,6 +129,46 @@ func DecryptAES(secret string, data string) (*models.Payload, error) {
 	return aes.Decrypt(secret, data)
 }
 
+func EncryptAESWithAAD(secret string, data, additionalData []byte) (*models.Payload, error) {
+	return aes.EncryptWithAAD(secret, data, additionalData)
+}
+
+func DecryptAESWithAAD(secret string, data string, additionalData []byte) (*models.Payload, error) {
+	return aes.DecryptWithAAD(secret, data, additionalData)
+}
+
+func EncryptAESNamed(keyName string, data []byte) (*models.Payload, error) {
+	return aes.EncryptNamed(keyName, data)
+}
+
+func DecryptAESNamed(data string) (*models.Payload, error) {
+	return aes.DecryptNamed(data)
+}
+
+func EncryptAESDeterministic(keyName string, data []byte) (*models.Payload, error) {
+	return aes.EncryptDeterministic(keyName, data)
+}
+
+func EncryptAESWithPassphrase(passphrase string, data []byte) (*models.Payload, error) {
+	return aes.EncryptWithPassphrase(passphrase, data)
+}
+
+func EncryptStreamRSA(w io.Writer, r io.Reader, keyName string) error {
+	return rsa.EncryptStream(w, r, keyName)
+}
+
+func DecryptStreamRSA(w io.Writer, r io.Reader) error {
+	return rsa.DecryptStream(w, r)
+}
+
+func EncryptStreamAES(w io.Writer, r io.Reader, secret string) error {
+	return aes.EncryptStream(w, r, secret)
+}
+
+func DecryptStreamAES(w io.Writer, r io.Reader, secret string) error {
+	return aes.DecryptStream(w, r, secret)
+}
+
 func LoadKeysFromEnv() error {
 	return config.LoadKeysFromEnv()
 }
@@ -31,6 +177,50 @@ func LoadKeysFromFile(keyName, keyPath string) error {
 	return config.LoadKeysFromFile(keyName, keyPath)
 }
 
+func LoadKeysFromDir(dir string) error {
+	return config.LoadKeysFromDir(dir)
+}
+
+func ReloadKeysFromEnv() error {
+	return config.ReloadKeysFromEnv()
+}
+
+func LoadKeysFromEnvToStore(store models.KeyStore) error {
+	return config.LoadKeysFromEnvToStore(store)
+}
+
+func LoadKeysFromFileToStore(store models.KeyStore, keyName, keyPath string) error {
+	return config.LoadKeysFromFileToStore(store, keyName, keyPath)
+}
+
+func LoadKeysFromSource(ctx context.Context, source config.KeySource) error {
+	return source.Load(ctx, models.DefaultKeyStore())
+}
+
+func LoadSecretsFromEnv() error {
+	return config.LoadSecretsFromEnv()
+}
+
+func LoadSecretFromFile(keyName, secretPath string) error {
+	return config.LoadSecretFromFile(keyName, secretPath)
+}
+
+func ExportJWKS() ([]byte, error) {
+	return jwk.ExportJSON(models.DefaultKeyStore())
+}
+
+func ImportJWKS(store models.KeyStore, data []byte) error {
+	return jwk.Import(store, data)
+}
+
+func SetKeyState(keyName string, version int, state models.KeyState) error {
+	return models.SetKeyState(models.DefaultKeyStore(), keyName, version, state)
+}
+
+func NewKeyStore() models.KeyStore {
+	return models.NewMemoryKeyStore()
+}
+
 func ListKeys() *models.EncryptStruct {
 	return models.GetEncryptKeysMap()
 }
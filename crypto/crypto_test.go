@@ -5,11 +5,13 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	stdErrors "errors"
 	"fmt"
 	"os"
 	"testing"
 
 	"github.com/nected/go-lib/crypto/config"
+	"github.com/nected/go-lib/crypto/errors"
 	"github.com/nected/go-lib/crypto/models"
 )
 
@@ -56,9 +58,9 @@ func setupSuite(t *testing.T) func(t *testing.T) {
 func TestLoadKeysFromEnv(t *testing.T) {
 	teardownSuite := setupSuite(t)
 	defer teardownSuite(t)
+	invalidKeyVar := fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "TESTKEYINVALID")
 	tests := []struct {
 		name        string
-		wantErr     bool
 		keyName     string
 		keyExists   bool
 		version     int
@@ -67,7 +69,6 @@ func TestLoadKeysFromEnv(t *testing.T) {
 	}{
 		{
 			name:        "TestLoadKeysFromEnv version 1 - No errors",
-			wantErr:     false,
 			keyName:     "TESTKEY",
 			keyExists:   true,
 			version:     1,
@@ -76,7 +77,6 @@ func TestLoadKeysFromEnv(t *testing.T) {
 		},
 		{
 			name:        "TestLoadKeysFromEnv version 2 - No errors",
-			wantErr:     false,
 			keyName:     "TESTKEY",
 			keyExists:   true,
 			version:     2,
@@ -85,7 +85,6 @@ func TestLoadKeysFromEnv(t *testing.T) {
 		},
 		{
 			name:        "TestLoadKeysFromEnv - error",
-			wantErr:     false,
 			keyName:     "TESTKEYA",
 			keyExists:   false,
 			version:     1,
@@ -95,15 +94,11 @@ func TestLoadKeysFromEnv(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			// TESTKEYINVALID is reported while the other keys still load
 			err := LoadKeysFromEnv()
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("LoadKeysFromEnv() error = %v, wantErr %v", err, tt.wantErr)
-				}
-				return
-			}
-			if err != nil {
-				t.Errorf("LoadKeysFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			var envErr *config.KeyEnvError
+			if !stdErrors.As(err, &envErr) || len(envErr.Vars) != 1 || envErr.Vars[0].Name != invalidKeyVar {
+				t.Errorf("LoadKeysFromEnv() error = %v, want only %v reported", err, invalidKeyVar)
 				return
 			}
 			info := models.GetEncryptionKey(tt.keyName, tt.version)
@@ -297,3 +292,290 @@ func TestEncryptRSA(t *testing.T) {
 		})
 	}
 }
+
+func TestEncryptRSAWithStore(t *testing.T) {
+	privKeyPath := t.TempDir() + "/tenant.pem"
+	if err := os.WriteFile(privKeyPath, []byte(generatePrivateKey()), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tenantA := NewKeyStore()
+	tenantB := NewKeyStore()
+	if err := LoadKeysFromFileToStore(tenantA, "TENANTKEY", privKeyPath); err != nil {
+		t.Fatalf("LoadKeysFromFileToStore() error = %v", err)
+	}
+
+	got, err := EncryptRSAWithStore(tenantA, "TENANTKEY", []byte("data"))
+	if err != nil {
+		t.Fatalf("EncryptRSAWithStore() error = %v", err)
+	}
+	if got.KeyName != "TENANTKEY" || got.KeyVersion != 1 {
+		t.Fatalf("EncryptRSAWithStore() = %v, want key TENANTKEY version 1", got)
+	}
+
+	payload, err := DecryptRSAWithStore(tenantA, got.String())
+	if err != nil {
+		t.Fatalf("DecryptRSAWithStore() error = %v", err)
+	}
+	if payload.Data != "data" {
+		t.Errorf("DecryptRSAWithStore() = %v, want %v", payload.Data, "data")
+	}
+
+	// the key is unknown to the other tenant so the data is passed through
+	payload, err = DecryptRSAWithStore(tenantB, got.String())
+	if err != nil {
+		t.Fatalf("DecryptRSAWithStore() error = %v", err)
+	}
+	if payload.Data != got.String() {
+		t.Errorf("DecryptRSAWithStore() = %v, want %v", payload.Data, got.String())
+	}
+
+	if models.GetEncryptionKey("TENANTKEY", 1) != nil {
+		t.Errorf("TENANTKEY leaked into the default store")
+	}
+}
+
+func TestReloadKeysFromEnv(t *testing.T) {
+	teardownSuite := setupSuite(t)
+	defer teardownSuite(t)
+	invalidKeyVar := fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "TESTKEYINVALID")
+	os.Unsetenv(invalidKeyVar)
+	if err := LoadKeysFromEnv(); err != nil {
+		t.Fatalf("LoadKeysFromEnv() error = %v", err)
+	}
+
+	payload, err := EncryptRSA("TESTKEY", []byte("data"))
+	if err != nil {
+		t.Fatalf("EncryptRSA() error = %v", err)
+	}
+	encrypted := payload.String()
+
+	// decrypt continuously while the key set is reloaded underneath
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			got, err := DecryptRSA(encrypted)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if got.Data != "data" {
+				errs <- fmt.Errorf("DecryptRSA() = %v, want %v", got.Data, "data")
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		if err := ReloadKeysFromEnv(); err != nil {
+			t.Errorf("ReloadKeysFromEnv() error = %v", err)
+		}
+	}
+	close(done)
+	if err := <-errs; err != nil {
+		t.Errorf("decrypt during reload failed: %v", err)
+	}
+
+	// keys removed from the environment disappear on the next reload
+	os.Unsetenv(fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "TESTKEY_2"))
+	if err := ReloadKeysFromEnv(); err != nil {
+		t.Fatalf("ReloadKeysFromEnv() error = %v", err)
+	}
+	if models.GetEncryptionKey("TESTKEY", 2) != nil {
+		t.Errorf("TESTKEY version 2 still present after reload")
+	}
+	if models.GetEncryptionKey("TESTKEY", 1) == nil {
+		t.Errorf("TESTKEY version 1 missing after reload")
+	}
+
+	// a revoked key stays revoked across reloads
+	if err := SetKeyState("TESTKEY", 1, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if err := ReloadKeysFromEnv(); err != nil {
+		t.Fatalf("ReloadKeysFromEnv() error = %v", err)
+	}
+	if keyInfo := models.GetEncryptionKey("TESTKEY", 1); keyInfo == nil || keyInfo.GetState() != models.KeyStateRevoked {
+		t.Errorf("GetEncryptionKey() = %v, want TESTKEY version 1 still revoked", keyInfo)
+	}
+
+	// a key that fails to load keeps the current key set
+	os.Setenv(invalidKeyVar, "lkajds")
+	os.Unsetenv(fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "TESTKEY_1"))
+	var envErr *config.KeyEnvError
+	if err := ReloadKeysFromEnv(); !stdErrors.As(err, &envErr) {
+		t.Errorf("ReloadKeysFromEnv() error = %v, want a *KeyEnvError", err)
+	}
+	if models.GetEncryptionKey("TESTKEY", 1) == nil {
+		t.Errorf("TESTKEY version 1 missing after a failed reload")
+	}
+}
+
+func TestEncryptRSAEnvelope(t *testing.T) {
+	teardownSuite := setupSuite(t)
+	LoadKeysFromEnv()
+	defer teardownSuite(t)
+
+	got, err := EncryptRSAEnvelope("TESTKEY", []byte("data"))
+	if err != nil {
+		t.Fatalf("EncryptRSAEnvelope() error = %v", err)
+	}
+
+	// DecryptRSA detects the envelope format next to the legacy one
+	payload, err := DecryptRSA(got.String())
+	if err != nil {
+		t.Fatalf("DecryptRSA() error = %v", err)
+	}
+	if payload.Data != "data" || payload.KeyType != models.KeyTypeRSAEnvelope {
+		t.Errorf("DecryptRSA() = %v, want envelope payload with data", payload)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	teardownSuite := setupSuite(t)
+	LoadKeysFromEnv()
+	defer teardownSuite(t)
+
+	models.SetDefaultSecretStore(nil)
+	defer models.SetDefaultSecretStore(nil)
+	t.Setenv(config.SECRET_ENV_PREFIX+"_TESTKEY_1", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err := LoadSecretsFromEnv(); err != nil {
+		t.Fatalf("LoadSecretsFromEnv() error = %v", err)
+	}
+
+	rsaPayload, err := EncryptRSA("TESTKEY", []byte("rsa data"))
+	if err != nil {
+		t.Fatalf("EncryptRSA() error = %v", err)
+	}
+	// the secret shares its name with the RSA key
+	aesPayload, err := EncryptAESNamed("TESTKEY", []byte("aes data"))
+	if err != nil {
+		t.Fatalf("EncryptAESNamed() error = %v", err)
+	}
+	sivPayload, err := EncryptAESDeterministic("TESTKEY", []byte("siv data"))
+	if err != nil {
+		t.Fatalf("EncryptAESDeterministic() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		want     string
+		wantType models.KeyType
+	}{
+		{"RSA", rsaPayload.String(), "rsa data", models.KeyTypeRSA},
+		{"named AES", aesPayload.String(), "aes data", models.KeyTypeAES},
+		{"AES-SIV", sivPayload.String(), "siv data", models.KeyTypeAESSIV},
+		{"plain text", "plain data", "plain data", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decrypt(tt.data)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if got.Data != tt.want || got.KeyType != tt.wantType {
+				t.Errorf("Decrypt() = %v, want %v of type %q", got, tt.want, tt.wantType)
+			}
+		})
+	}
+
+	// RSA decryption leaves named AES payloads alone
+	got, err := DecryptRSA(aesPayload.String())
+	if err != nil || got.Data != aesPayload.String() {
+		t.Errorf("DecryptRSA() = %v, %v, want the payload unchanged", got, err)
+	}
+
+	for _, tt := range tests {
+		t.Run("strict "+tt.name, func(t *testing.T) {
+			got, err := DecryptStrict(tt.data)
+			if tt.wantType == "" {
+				if !stdErrors.Is(err, errors.ErrMalformedHeader) {
+					t.Errorf("DecryptStrict() error = %v, want %v", err, errors.ErrMalformedHeader)
+				}
+				return
+			}
+			if err != nil || got.Data != tt.want {
+				t.Errorf("DecryptStrict() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	teardownSuite := setupSuite(t)
+	LoadKeysFromEnv()
+	defer teardownSuite(t)
+
+	data := []byte(`{"event":"created"}`)
+	signature, err := Sign("TESTKEY", data)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if err := Verify(signature, data); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// a signature is not mistaken for RSA ciphertext
+	got, err := Decrypt(signature)
+	if err != nil || got.Data != signature {
+		t.Errorf("Decrypt() = %v, %v, want the signature unchanged", got, err)
+	}
+}
+
+func TestRotateDocument(t *testing.T) {
+	models.SetDefaultSecretStore(nil)
+	defer models.SetDefaultSecretStore(nil)
+	t.Setenv(config.SECRET_ENV_PREFIX+"_DOCSECRET_1", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err := LoadSecretsFromEnv(); err != nil {
+		t.Fatalf("LoadSecretsFromEnv() error = %v", err)
+	}
+	aesPayload, err := EncryptAESNamed("DOCSECRET", []byte("aes data"))
+	if err != nil {
+		t.Fatalf("EncryptAESNamed() error = %v", err)
+	}
+	sivPayload, err := EncryptAESDeterministic("DOCSECRET", []byte("siv data"))
+	if err != nil {
+		t.Fatalf("EncryptAESDeterministic() error = %v", err)
+	}
+	doc := map[string]any{
+		"aes":    aesPayload.String(),
+		"nested": map[string]any{"siv": []any{sivPayload.String()}},
+		"plain":  "plain data",
+	}
+
+	t.Setenv(config.SECRET_ENV_PREFIX+"_DOCSECRET_2", "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=")
+	if err := LoadSecretsFromEnv(); err != nil {
+		t.Fatalf("LoadSecretsFromEnv() error = %v", err)
+	}
+	rotated, err := RotateDocument(doc)
+	if err != nil || rotated != 2 {
+		t.Fatalf("RotateDocument() = %v, %v, want 2", rotated, err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		data any
+		want string
+	}{
+		{"named AES", doc["aes"], "aes data"},
+		{"AES-SIV", doc["nested"].(map[string]any)["siv"].([]any)[0], "siv data"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decrypt(tt.data.(string))
+			if err != nil || got.Data != tt.want || got.KeyVersion != 2 {
+				t.Errorf("Decrypt() = %v, %v, want %v with version 2", got, err, tt.want)
+			}
+		})
+	}
+	if doc["plain"] != "plain data" {
+		t.Errorf("RotateDocument() changed plain text to %v", doc["plain"])
+	}
+}
@@ -3,6 +3,99 @@ package errors
 import "fmt"
 
 var (
-	ErrEmptyData   = fmt.Errorf("data is empty")
-	ErrInvalidData = fmt.Errorf("data is invalid")
+	ErrEmptyData     = fmt.Errorf("data is empty")
+	ErrInvalidData   = fmt.Errorf("data is invalid")
+	ErrTruncatedData = fmt.Errorf("data is truncated")
+	// ErrMalformedHeader is returned when data does not start with a valid
+	// "$keyName$keyVersion$" header.
+	ErrMalformedHeader = fmt.Errorf("header is malformed")
+	// ErrInvalidPath is returned for a document path that can not be parsed
+	// or that does not fit the document, e.g. an index into an object.
+	ErrInvalidPath = fmt.Errorf("path is invalid")
+
+	ErrAuthenticationFailed = fmt.Errorf("authentication failed: wrong key, associated data mismatch or tampered data")
+	// ErrDecryptionFailed is the only error returned by the hardened decrypt
+	// functions, whatever made decryption fail.
+	ErrDecryptionFailed = fmt.Errorf("decryption failed")
+	ErrInvalidSignature = fmt.Errorf("signature is invalid")
+
+	ErrInvalidKeyInfo = fmt.Errorf("key info is invalid")
+	ErrKeyNotFound    = fmt.Errorf("key not found")
+	// ErrVersionNotFound is returned when a key is loaded but not the
+	// requested version of it. A *KeyError carrying it also matches
+	// ErrKeyNotFound.
+	ErrVersionNotFound = fmt.Errorf("key version not found")
+	ErrKeyRevoked      = fmt.Errorf("key is revoked")
+	ErrNoPrivateKey    = fmt.Errorf("private key is not available")
+
+	ErrUnsupportedKeyAlgorithm = fmt.Errorf("key algorithm is not supported")
+
+	ErrTokenMalformed        = fmt.Errorf("token is malformed")
+	ErrTokenExpired          = fmt.Errorf("token is expired")
+	ErrTokenNotValidYet      = fmt.Errorf("token is not valid yet")
+	ErrTokenUsedBeforeIssued = fmt.Errorf("token used before issued")
+	ErrTokenInvalidAudience  = fmt.Errorf("token has invalid audience")
+	ErrTokenInvalidIssuer    = fmt.Errorf("token has invalid issuer")
+	ErrInvalidClaims         = fmt.Errorf("claims are nil")
 )
+
+// NoPrivateKeyError is returned when a key loaded from a public key or
+// certificate is used to decrypt or sign. It matches ErrNoPrivateKey.
+type NoPrivateKeyError struct {
+	KeyName    string
+	KeyVersion int
+}
+
+func (e *NoPrivateKeyError) Error() string {
+	return fmt.Sprintf("%v: %s version %d only holds a public key", ErrNoPrivateKey, e.KeyName, e.KeyVersion)
+}
+
+func (e *NoPrivateKeyError) Is(target error) bool {
+	return target == ErrNoPrivateKey
+}
+
+// KeyError reports that a version of a key could not be used. KeyVersion is 0
+// when the latest active version was requested. It unwraps to Err, one of
+// ErrKeyNotFound, ErrVersionNotFound and ErrKeyRevoked.
+type KeyError struct {
+	KeyName    string
+	KeyVersion int
+	Err        error
+}
+
+func (e *KeyError) Error() string {
+	if e.KeyVersion == 0 {
+		return fmt.Sprintf("%v: %s", e.Err, e.KeyName)
+	}
+	return fmt.Sprintf("%v: %s version %d", e.Err, e.KeyName, e.KeyVersion)
+}
+
+// Is lets callers that do not tell a missing version from a missing key check
+// for ErrKeyNotFound only.
+func (e *KeyError) Is(target error) bool {
+	return target == ErrKeyNotFound && e.Err == ErrVersionNotFound
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is returned when data is not validly encoded. It matches
+// ErrInvalidData and unwraps to the error of the decoder, e.g. a
+// base64.CorruptInputError.
+type DecodeError struct {
+	Encoding string
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v: %s: %v", ErrInvalidData, e.Encoding, e.Err)
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrInvalidData
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,193 @@
+package crypto
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nected/go-lib/crypto/models"
+	"github.com/nected/go-lib/crypto/rsa"
+)
+
+const (
+	// encryptTagName is the struct tag naming the key a field is encrypted
+	// with.
+	encryptTagName = "encrypt"
+	// encryptTagEnvelope is the tag option selecting envelope encryption,
+	// e.g. `encrypt:"KEYNAME,envelope"`.
+	encryptTagEnvelope = "envelope"
+)
+
+// fieldTransform encrypts or decrypts a single tagged value.
+type fieldTransform func(tag fieldTag, data string) (string, error)
+
+type fieldTag struct {
+	keyName  string
+	envelope bool
+}
+
+// EncryptStruct encrypts, in place, every string and []byte field of the
+// struct pointed to by v that carries an `encrypt:"keyName"` tag. Nested
+// structs, pointers, slices, arrays, maps and interfaces are walked
+// recursively; a tag on a slice or map applies to its elements or values.
+//
+// Empty values and values that are already encrypted are left untouched.
+// Add the envelope option, `encrypt:"keyName,envelope"`, to use
+// EncryptRSAEnvelope for large fields.
+func EncryptStruct(v any) error {
+	return EncryptStructWithStore(models.DefaultKeyStore(), v)
+}
+
+// EncryptStructWithStore behaves like EncryptStruct but looks keys up in
+// store instead of the default store.
+func EncryptStructWithStore(store models.KeyStore, v any) error {
+	return transformStruct(v, func(tag fieldTag, data string) (string, error) {
+		encrypt := rsa.EncryptWithStore
+		if tag.envelope {
+			encrypt = rsa.EncryptEnvelopeWithStore
+		}
+		payload, err := encrypt(store, tag.keyName, []byte(data))
+		if err != nil {
+			return "", err
+		}
+		return payload.String(), nil
+	})
+}
+
+// DecryptStruct reverses EncryptStruct. Tagged values that are not encrypted
+// are left untouched.
+func DecryptStruct(v any) error {
+	return DecryptStructWithStore(models.DefaultKeyStore(), v)
+}
+
+// DecryptStructWithStore behaves like DecryptStruct but looks keys up in
+// store instead of the default store.
+func DecryptStructWithStore(store models.KeyStore, v any) error {
+	return transformStruct(v, func(_ fieldTag, data string) (string, error) {
+		payload, err := rsa.DecryptWithStore(store, data)
+		if err != nil {
+			return "", err
+		}
+		return payload.Data, nil
+	})
+}
+
+func transformStruct(v any, transform fieldTransform) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a non-nil pointer to a struct, got %T", v)
+	}
+	w := &structWalker{
+		transform: transform,
+		visited:   map[visitKey]bool{{val.Pointer(), val.Type()}: true},
+	}
+	return w.processValue(val.Elem(), fieldTag{}, val.Elem().Type().Name())
+}
+
+type structWalker struct {
+	transform fieldTransform
+	// visited guards against cycles through pointers
+	visited map[visitKey]bool
+}
+
+// visitKey identifies a pointer by address and type: a pointer to a struct
+// and a pointer to its first field share an address but are different
+// values to walk.
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// recursive function to process nested values
+func (w *structWalker) processValue(val reflect.Value, tag fieldTag, path string) error {
+	switch val.Kind() {
+	case reflect.String:
+		if tag.keyName == "" || val.Len() == 0 || !val.CanSet() {
+			return nil
+		}
+		transformed, err := w.transform(tag, val.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		val.SetString(transformed)
+	case reflect.Slice:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			if tag.keyName == "" || val.Len() == 0 || !val.CanSet() {
+				return nil
+			}
+			transformed, err := w.transform(tag, string(val.Bytes()))
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			val.SetBytes([]byte(transformed))
+			return nil
+		}
+		for i := 0; i < val.Len(); i++ {
+			if err := w.processValue(val.Index(i), tag, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if err := w.processValue(val.Index(i), tag, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := val.MapRange()
+		for iter.Next() {
+			// map values are not addressable, so work on a copy
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			if err := w.processValue(elem, tag, fmt.Sprintf("%s[%v]", path, iter.Key())); err != nil {
+				return err
+			}
+			val.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.Ptr:
+		key := visitKey{val.Pointer(), val.Type()}
+		if val.IsNil() || w.visited[key] {
+			return nil
+		}
+		w.visited[key] = true
+		return w.processValue(val.Elem(), tag, path)
+	case reflect.Interface:
+		if val.IsNil() || !val.CanSet() {
+			return nil
+		}
+		// values held by interfaces are not addressable, so work on a copy
+		elem := reflect.New(val.Elem().Type()).Elem()
+		elem.Set(val.Elem())
+		if err := w.processValue(elem, tag, path); err != nil {
+			return err
+		}
+		val.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Field(i)
+			structField := val.Type().Field(i)
+			if !structField.IsExported() {
+				continue
+			}
+			fieldPath := structField.Name
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+			if err := w.processValue(field, parseFieldTag(structField.Tag.Get(encryptTagName)), fieldPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func parseFieldTag(tag string) fieldTag {
+	parts := strings.Split(tag, ",")
+	fTag := fieldTag{keyName: strings.TrimSpace(parts[0])}
+	for _, option := range parts[1:] {
+		if strings.TrimSpace(option) == encryptTagEnvelope {
+			fTag.envelope = true
+		}
+	}
+	return fTag
+}
@@ -0,0 +1,132 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+type testAddress struct {
+	Street string `encrypt:"FIELDKEY"`
+	City   string
+}
+
+type testUser struct {
+	Name     string
+	Email    string            `encrypt:"FIELDKEY"`
+	Document []byte            `encrypt:"FIELDKEY,envelope"`
+	Phones   []string          `encrypt:"FIELDKEY"`
+	Notes    map[string]string `encrypt:"FIELDKEY"`
+	Extra    any               `encrypt:"FIELDKEY"`
+	Address  testAddress
+	Previous []*testAddress
+	Empty    string `encrypt:"FIELDKEY"`
+	Self     *testUser
+	secret   string `encrypt:"FIELDKEY"`
+}
+
+func newFieldTestStore(t *testing.T) models.KeyStore {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	store := NewKeyStore()
+	if err := store.Put(models.KeyInfo{Name: "FIELDKEY", Version: 1, PrivKey: privateKey, PubKey: &privateKey.PublicKey}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	return store
+}
+
+func TestEncryptStructWithStore(t *testing.T) {
+	store := newFieldTestStore(t)
+	user := &testUser{
+		Name:     "name",
+		Email:    "a@b.c",
+		Document: []byte("document"),
+		Phones:   []string{"123", "456"},
+		Notes:    map[string]string{"note": "text"},
+		Extra:    "extra",
+		Address:  testAddress{Street: "street", City: "city"},
+		Previous: []*testAddress{{Street: "old street", City: "old city"}, nil},
+		secret:   "unexported",
+	}
+	user.Self = user
+
+	if err := EncryptStructWithStore(store, user); err != nil {
+		t.Fatalf("EncryptStructWithStore() error = %v", err)
+	}
+
+	encrypted := []string{user.Email, string(user.Document), user.Phones[0], user.Phones[1], user.Notes["note"], user.Extra.(string), user.Address.Street, user.Previous[0].Street}
+	for _, val := range encrypted {
+		payload, err := DecryptRSAWithStore(store, val)
+		if err != nil || payload.KeyName != "FIELDKEY" {
+			t.Errorf("value %q is not encrypted with FIELDKEY", val)
+		}
+	}
+	if user.Name != "name" || user.Address.City != "city" || user.Previous[0].City != "old city" || user.Empty != "" || user.secret != "unexported" {
+		t.Errorf("EncryptStructWithStore() changed untagged values: %+v", user)
+	}
+	if payload, _ := DecryptRSAWithStore(store, string(user.Document)); payload.KeyType != models.KeyTypeRSAEnvelope {
+		t.Errorf("Document KeyType = %v, want %v", payload.KeyType, models.KeyTypeRSAEnvelope)
+	}
+
+	// encrypting twice keeps the already encrypted values
+	email := user.Email
+	if err := EncryptStructWithStore(store, user); err != nil {
+		t.Fatalf("EncryptStructWithStore() error = %v", err)
+	}
+	if user.Email != email {
+		t.Errorf("EncryptStructWithStore() re-encrypted an encrypted value")
+	}
+
+	if err := DecryptStructWithStore(store, user); err != nil {
+		t.Fatalf("DecryptStructWithStore() error = %v", err)
+	}
+	if user.Email != "a@b.c" || !bytes.Equal(user.Document, []byte("document")) || user.Phones[1] != "456" ||
+		user.Notes["note"] != "text" || user.Extra != "extra" || user.Address.Street != "street" || user.Previous[0].Street != "old street" {
+		t.Errorf("DecryptStructWithStore() = %+v, want original values", user)
+	}
+}
+
+type testPair struct {
+	Note  string
+	Other string
+}
+
+type testAliased struct {
+	Pair *testPair
+	Note *string `encrypt:"FIELDKEY"`
+}
+
+// TestEncryptStructFieldPointer checks that a pointer to the first field of a
+// struct is walked even though it shares its address with a pointer to the
+// struct.
+func TestEncryptStructFieldPointer(t *testing.T) {
+	store := newFieldTestStore(t)
+	pair := &testPair{Note: "note", Other: "other"}
+	aliased := &testAliased{Pair: pair, Note: &pair.Note}
+
+	if err := EncryptStructWithStore(store, aliased); err != nil {
+		t.Fatalf("EncryptStructWithStore() error = %v", err)
+	}
+	if payload, err := DecryptRSAWithStore(store, *aliased.Note); err != nil || payload.KeyName != "FIELDKEY" {
+		t.Errorf("Note %q is not encrypted with FIELDKEY", *aliased.Note)
+	}
+	if pair.Other != "other" {
+		t.Errorf("EncryptStructWithStore() changed untagged value %q", pair.Other)
+	}
+}
+
+func TestEncryptStructInvalidInput(t *testing.T) {
+	store := newFieldTestStore(t)
+	var nilUser *testUser
+	for _, v := range []any{testUser{}, nilUser, "string", nil} {
+		if err := EncryptStructWithStore(store, v); err == nil {
+			t.Errorf("EncryptStructWithStore(%T) error = nil, want error", v)
+		}
+	}
+}
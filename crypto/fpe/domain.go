@@ -0,0 +1,146 @@
+package fpe
+
+import (
+	"fmt"
+
+	"github.com/nected/go-lib/crypto/errors"
+)
+
+// Alphabet maps the characters of a domain to the numerals FF1 works on:
+// the i-th character of the alphabet is the numeral i.
+type Alphabet struct {
+	chars []rune
+	index map[rune]uint16
+}
+
+var (
+	// Base10 holds the decimal digits.
+	Base10 = MustAlphabet("0123456789")
+	// Base36 holds the digits and the lower case letters, the alphabet of
+	// the radix 36 NIST samples.
+	Base36 = MustAlphabet("0123456789abcdefghijklmnopqrstuvwxyz")
+	// Base62 holds the digits and the lower and upper case letters.
+	Base62 = MustAlphabet("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+)
+
+// NewAlphabet returns the alphabet of the distinct characters of chars, in
+// order. It needs between 2 and 65536 characters.
+func NewAlphabet(chars string) (*Alphabet, error) {
+	a := &Alphabet{index: make(map[rune]uint16)}
+	for _, c := range chars {
+		if _, ok := a.index[c]; ok {
+			return nil, fmt.Errorf("duplicate alphabet character %q", c)
+		}
+		if len(a.chars) == maxRadix {
+			return nil, fmt.Errorf("alphabet has more than %d characters", maxRadix)
+		}
+		a.index[c] = uint16(len(a.chars))
+		a.chars = append(a.chars, c)
+	}
+	if len(a.chars) < 2 {
+		return nil, fmt.Errorf("alphabet %q has less than 2 characters", chars)
+	}
+	return a, nil
+}
+
+// MustAlphabet is like NewAlphabet but panics if chars is not a valid
+// alphabet.
+func MustAlphabet(chars string) *Alphabet {
+	a, err := NewAlphabet(chars)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Radix returns the number of characters of the alphabet.
+func (a *Alphabet) Radix() int {
+	return len(a.chars)
+}
+
+// Contains reports whether c is a character of the alphabet.
+func (a *Alphabet) Contains(c rune) bool {
+	_, ok := a.index[c]
+	return ok
+}
+
+// Domain describes the values a named FPE key maps onto themselves.
+type Domain struct {
+	// Alphabet holds the characters that are encrypted.
+	Alphabet *Alphabet
+	// Passthrough keeps characters outside Alphabet in place, e.g. the
+	// separators of a phone number. When false such characters are an error.
+	Passthrough bool
+	// Scope, when set, returns the part of the value that is encrypted; the
+	// rest is kept as is. It returns nil if the value is not in the domain.
+	Scope func(value []rune) []rune
+}
+
+var (
+	// Digits encrypts the digits of a value and keeps everything else, so
+	// "+1 (555) 010-4477" stays a phone number and "4111 1111 1111 1111" a
+	// card number. At least 6 digits are needed.
+	Digits = Domain{Alphabet: Base10, Passthrough: true}
+	// Alphanumeric encrypts the ASCII letters and digits of a value and keeps
+	// everything else. At least 4 letters or digits are needed.
+	Alphanumeric = Domain{Alphabet: Base62, Passthrough: true}
+	// EmailLocalPart encrypts the letters and digits before the last "@" of
+	// an email address. The dots, plus signs and other punctuation of the
+	// local part and the whole domain are kept, so the result is still a
+	// valid address of the same mail domain. At least 4 letters or digits are
+	// needed.
+	EmailLocalPart = Domain{Alphabet: Base62, Passthrough: true, Scope: emailLocalPart}
+)
+
+func emailLocalPart(value []rune) []rune {
+	for i := len(value) - 1; i >= 0; i-- {
+		if value[i] == '@' {
+			return value[:i]
+		}
+	}
+	return nil
+}
+
+// numerals returns the scope of value, the positions in it of the characters
+// to encrypt and their numerals.
+func (d Domain) numerals(value []rune) ([]rune, []int, []uint16, error) {
+	scope := value
+	if d.Scope != nil {
+		if scope = d.Scope(value); scope == nil {
+			return nil, nil, nil, fmt.Errorf("%w: value is not in the domain", errors.ErrInvalidData)
+		}
+	}
+	positions := make([]int, 0, len(scope))
+	x := make([]uint16, 0, len(scope))
+	for i, c := range scope {
+		numeral, ok := d.Alphabet.index[c]
+		if !ok {
+			if d.Passthrough {
+				continue
+			}
+			return nil, nil, nil, fmt.Errorf("%w: character %q is not in the alphabet", errors.ErrInvalidData, c)
+		}
+		positions = append(positions, i)
+		x = append(x, numeral)
+	}
+	return scope, positions, x, nil
+}
+
+// transform applies fn to the numerals of value and puts the resulting
+// characters back in their positions.
+func (d Domain) transform(value string, fn func([]uint16) ([]uint16, error)) (string, error) {
+	runes := []rune(value)
+	scope, positions, x, err := d.numerals(runes)
+	if err != nil {
+		return "", err
+	}
+	y, err := fn(x)
+	if err != nil {
+		return "", err
+	}
+	// scope shares its characters with runes
+	for i, position := range positions {
+		scope[position] = d.Alphabet.chars[y[i]]
+	}
+	return string(runes), nil
+}
@@ -0,0 +1,179 @@
+package fpe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/nected/go-lib/crypto/errors"
+)
+
+const (
+	// ff1Rounds is the number of Feistel rounds of FF1.
+	ff1Rounds = 10
+	// ff1MinDomainSize is the smallest number of values FF1 may encrypt:
+	// radix^len must be at least a million (NIST SP 800-38G Rev. 1).
+	ff1MinDomainSize = 1_000_000
+	// maxRadix is the largest radix supported by FF1.
+	maxRadix = 1 << 16
+)
+
+// FF1 is the format-preserving cipher FF1 of NIST SP 800-38G over numerals
+// of a fixed radix.
+type FF1 struct {
+	block cipher.Block
+	radix int
+}
+
+// NewFF1 returns an FF1 cipher for numerals in [0, radix). key is a 16, 24 or
+// 32 byte AES key and radix is between 2 and 65536.
+func NewFF1(key []byte, radix int) (*FF1, error) {
+	if radix < 2 || radix > maxRadix {
+		return nil, fmt.Errorf("invalid FF1 radix %d", radix)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &FF1{block: block, radix: radix}, nil
+}
+
+// Encrypt encrypts the numerals x under tweak and returns as many numerals.
+// The numerals must be below the radix and radix^len(x) at least a million.
+func (f *FF1) Encrypt(x []uint16, tweak []byte) ([]uint16, error) {
+	return f.crypt(x, tweak, true)
+}
+
+// Decrypt reverses Encrypt with the same tweak.
+func (f *FF1) Decrypt(x []uint16, tweak []byte) ([]uint16, error) {
+	return f.crypt(x, tweak, false)
+}
+
+// crypt implements algorithms 7 and 8 of NIST SP 800-38G.
+func (f *FF1) crypt(x []uint16, tweak []byte, encrypt bool) ([]uint16, error) {
+	if err := f.validate(x); err != nil {
+		return nil, err
+	}
+	radix := big.NewInt(int64(f.radix))
+	n, t := len(x), len(tweak)
+	u := n / 2
+	v := n - u
+
+	// b bytes hold any number of v numerals, d bytes feed the round function
+	b := (new(big.Int).Sub(new(big.Int).Exp(radix, big.NewInt(int64(v)), nil), big.NewInt(1)).BitLen() + 7) / 8
+	d := 4*((b+3)/4) + 4
+
+	p := []byte{1, 2, 1, 0, 0, 0, 10, byte(u), 0, 0, 0, 0, 0, 0, 0, 0}
+	p[3], p[4], p[5] = byte(f.radix>>16), byte(f.radix>>8), byte(f.radix)
+	binary.BigEndian.PutUint32(p[8:], uint32(n))
+	binary.BigEndian.PutUint32(p[12:], uint32(t))
+
+	// Q = T || [0]^((-t-b-1) mod 16) || [i] || [NUM(B)]^b
+	padding := ((-t-b-1)%16 + 16) % 16
+	q := make([]byte, t+padding+1+b)
+	copy(q, tweak)
+
+	a, bNum := f.num(x[:u]), f.num(x[u:])
+	modU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+	y := new(big.Int)
+	for round := 0; round < ff1Rounds; round++ {
+		i := round
+		if !encrypt {
+			i = ff1Rounds - 1 - round
+		}
+		// the round function reads B when encrypting and A when decrypting
+		in := bNum
+		if !encrypt {
+			in = a
+		}
+		q[t+padding] = byte(i)
+		in.FillBytes(q[t+padding+1:])
+		y.SetBytes(f.roundOutput(p, q, d))
+
+		mod := modU
+		if i%2 == 1 {
+			mod = modV
+		}
+		c := new(big.Int)
+		if encrypt {
+			c.Add(a, y).Mod(c, mod)
+			a, bNum = bNum, c
+		} else {
+			c.Sub(bNum, y).Mod(c, mod)
+			bNum, a = a, c
+		}
+	}
+
+	out := make([]uint16, 0, n)
+	out = append(out, f.str(a, u)...)
+	return append(out, f.str(bNum, v)...), nil
+}
+
+func (f *FF1) validate(x []uint16) error {
+	if len(x) < 2 {
+		return fmt.Errorf("%w: FF1 input of %d numerals is too short", errors.ErrInvalidData, len(x))
+	}
+	domainSize := new(big.Int).Exp(big.NewInt(int64(f.radix)), big.NewInt(int64(len(x))), nil)
+	if domainSize.Cmp(big.NewInt(ff1MinDomainSize)) < 0 {
+		return fmt.Errorf("%w: FF1 input of %d numerals of radix %d is too short", errors.ErrInvalidData, len(x), f.radix)
+	}
+	for _, numeral := range x {
+		if int(numeral) >= f.radix {
+			return fmt.Errorf("%w: FF1 numeral %d out of radix %d", errors.ErrInvalidData, numeral, f.radix)
+		}
+	}
+	return nil
+}
+
+// roundOutput returns the first d bytes of the extended PRF output on P||Q.
+func (f *FF1) roundOutput(p, q []byte, d int) []byte {
+	// PRF is CBC-MAC with a zero IV
+	r := make([]byte, aes.BlockSize)
+	for _, data := range [][]byte{p, q} {
+		for i := 0; i < len(data); i += aes.BlockSize {
+			for j := 0; j < aes.BlockSize; j++ {
+				r[j] ^= data[i+j]
+			}
+			f.block.Encrypt(r, r)
+		}
+	}
+
+	// S = R || CIPH(R ⊕ [1]^16) || CIPH(R ⊕ [2]^16) || ...
+	s := append(make([]byte, 0, d+aes.BlockSize), r...)
+	block := make([]byte, aes.BlockSize)
+	for j := 1; len(s) < d; j++ {
+		copy(block, r)
+		counter := block[aes.BlockSize-8:]
+		binary.BigEndian.PutUint64(counter, binary.BigEndian.Uint64(counter)^uint64(j))
+		f.block.Encrypt(block, block)
+		s = append(s, block...)
+	}
+	return s[:d]
+}
+
+// num returns the number represented by the numerals x, most significant
+// first.
+func (f *FF1) num(x []uint16) *big.Int {
+	radix := big.NewInt(int64(f.radix))
+	n := new(big.Int)
+	for _, numeral := range x {
+		n.Mul(n, radix).Add(n, big.NewInt(int64(numeral)))
+	}
+	return n
+}
+
+// str returns the m numerals representing n, most significant first.
+func (f *FF1) str(n *big.Int, m int) []uint16 {
+	radix := big.NewInt(int64(f.radix))
+	n = new(big.Int).Set(n)
+	digit := new(big.Int)
+	x := make([]uint16, m)
+	for i := m - 1; i >= 0; i-- {
+		n.DivMod(n, radix, digit)
+		x[i] = uint16(digit.Int64())
+	}
+	return x
+}
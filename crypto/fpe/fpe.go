@@ -0,0 +1,137 @@
+package fpe
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Format-preserving encryption maps a value onto another value of the same
+// domain: a card number to a card number, a phone number to a phone number.
+// It uses FF1 (NIST SP 800-38G) keyed with named, versioned secrets loaded
+// like the AES secrets, e.g. with config.LoadSecretsFromEnv. The FF1 key is
+// derived from the secret, so a secret shared with aes.EncryptNamed still
+// yields an independent key, and the tweak is bound to the key name and
+// version.
+//
+// A ciphertext that looks like a plaintext has no room for a header, so
+// unlike the other payloads of this module it does not carry the key
+// version: Encrypt returns it and the caller stores it next to the value, or
+// passes it to Rotate when moving to a newer version.
+//
+// Like aes.EncryptDeterministic, FPE is deterministic and LEAKS EQUALITY: the
+// same value, tweak and key version always give the same ciphertext. Small
+// domains are also weak; FF1 refuses domains of less than a million values.
+
+// hkdfInfo separates FPE keys from any other key derived from a secret.
+const hkdfInfo = "go-lib fpe"
+
+// Encrypt encrypts value with FF1 under the latest active version of the
+// named secret. Only the characters of the domain are encrypted, the others
+// are kept in place.
+//
+// Parameters:
+//   - keyName: The name of the secret to use.
+//   - value: The value to encrypt.
+//   - domain: The domain of value, e.g. Digits or EmailLocalPart.
+//   - opts: WithStore and WithTweak.
+//
+// Returns:
+//   - string: The ciphertext, in the same domain as value.
+//   - int: The version of the secret, needed to decrypt the ciphertext.
+//   - error: errors.ErrKeyNotFound if the secret is not loaded, or
+//     errors.ErrInvalidData if value is not in the domain or too short.
+func Encrypt(keyName, value string, domain Domain, opts ...Option) (string, int, error) {
+	o := newOptions(opts)
+	keyInfo, err := models.FindKey(o.store, keyName, 0)
+	if err != nil {
+		return "", 0, err
+	}
+	ciphertext, err := crypt(keyInfo, value, domain, o, true)
+	if err != nil {
+		return "", 0, err
+	}
+	return ciphertext, keyInfo.GetVersion(), nil
+}
+
+// Decrypt decrypts a ciphertext produced by Encrypt with version keyVersion
+// of the named secret, in the same domain and with the same tweak.
+//
+// Returns errors.ErrKeyNotFound if the secret version is not loaded and
+// errors.ErrKeyRevoked if it has been revoked. A ciphertext decrypted with
+// the wrong key or tweak yields a wrong value, not an error.
+func Decrypt(keyName string, keyVersion int, ciphertext string, domain Domain, opts ...Option) (string, error) {
+	o := newOptions(opts)
+	keyInfo, err := models.FindDecryptionKey(o.store, keyName, keyVersion)
+	if err != nil {
+		return "", err
+	}
+	return crypt(keyInfo, ciphertext, domain, o, false)
+}
+
+// Rotate re-encrypts a ciphertext of version keyVersion with the latest
+// active version of the named secret. Ciphertexts already using the latest
+// version are returned unchanged.
+//
+// Returns:
+//   - string: The ciphertext.
+//   - int: The version of the returned ciphertext.
+//   - bool: Whether the ciphertext was re-encrypted.
+//   - error: An error if the ciphertext could not be decrypted or encrypted.
+func Rotate(keyName string, keyVersion int, ciphertext string, domain Domain, opts ...Option) (string, int, bool, error) {
+	o := newOptions(opts)
+	latest := models.LookupKey(o.store, keyName, 0)
+	if latest == nil || latest.GetVersion() <= keyVersion {
+		return ciphertext, keyVersion, false, nil
+	}
+	value, err := Decrypt(keyName, keyVersion, ciphertext, domain, opts...)
+	if err != nil {
+		return ciphertext, keyVersion, false, err
+	}
+	rotated, err := crypt(latest, value, domain, o, true)
+	if err != nil {
+		return ciphertext, keyVersion, false, err
+	}
+	return rotated, latest.GetVersion(), true, nil
+}
+
+func crypt(keyInfo *models.KeyInfo, value string, domain Domain, o *options, encrypt bool) (string, error) {
+	if domain.Alphabet == nil {
+		return "", fmt.Errorf("%w: domain has no alphabet", errors.ErrInvalidData)
+	}
+	key, err := fpeKey(keyInfo)
+	if err != nil {
+		return "", err
+	}
+	ff1, err := NewFF1(key, domain.Alphabet.Radix())
+	if err != nil {
+		return "", err
+	}
+	tweak := keyInfo.Label(o.tweak)
+	return domain.transform(value, func(x []uint16) ([]uint16, error) {
+		if encrypt {
+			return ff1.Encrypt(x, tweak)
+		}
+		return ff1.Decrypt(x, tweak)
+	})
+}
+
+// fpeKey derives the FF1 key of a version of a secret, of the secret's
+// length.
+func fpeKey(keyInfo *models.KeyInfo) ([]byte, error) {
+	secret := keyInfo.GetSecret()
+	switch len(secret) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("%w: %s has no 16, 24 or 32 byte secret", errors.ErrInvalidKeyInfo, keyInfo.KeyNameVersion())
+	}
+	key := make([]byte, len(secret))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(hkdfInfo)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
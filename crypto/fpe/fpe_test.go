@@ -0,0 +1,234 @@
+package fpe
+
+import (
+	"bytes"
+	stdErrors "errors"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// TestFF1 checks the FF1 samples of NIST SP 800-38G.
+func TestFF1(t *testing.T) {
+	const (
+		key128 = "2B7E151628AED2A6ABF7158809CF4F3C"
+		key192 = "2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F"
+		key256 = "2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94"
+	)
+	tests := []struct {
+		name       string
+		key        string
+		alphabet   *Alphabet
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		{"Sample 1", key128, Base10, "", "0123456789", "2433477484"},
+		{"Sample 2", key128, Base10, "39383736353433323130", "0123456789", "6124200773"},
+		{"Sample 3", key128, Base36, "3737373770717273373737", "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+		{"Sample 4", key192, Base10, "", "0123456789", "2830668132"},
+		{"Sample 5", key192, Base10, "39383736353433323130", "0123456789", "2496655549"},
+		{"Sample 6", key192, Base36, "3737373770717273373737", "0123456789abcdefghi", "xbj3kv35jrawxv32ysr"},
+		{"Sample 7", key256, Base10, "", "0123456789", "6657667009"},
+		{"Sample 8", key256, Base10, "39383736353433323130", "0123456789", "1001623463"},
+		{"Sample 9", key256, Base36, "3737373770717273373737", "0123456789abcdefghi", "xs8a0azh2avyalyzuwd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ff1, err := NewFF1(testutil.DecodeHex(t, tt.key), tt.alphabet.Radix())
+			if err != nil {
+				t.Fatalf("NewFF1() error = %v", err)
+			}
+			tweak := testutil.DecodeHex(t, tt.tweak)
+			domain := Domain{Alphabet: tt.alphabet}
+
+			got, err := domain.transform(tt.plaintext, func(x []uint16) ([]uint16, error) {
+				return ff1.Encrypt(x, tweak)
+			})
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if got != tt.ciphertext {
+				t.Errorf("Encrypt() = %v, want %v", got, tt.ciphertext)
+			}
+
+			got, err = domain.transform(tt.ciphertext, func(x []uint16) ([]uint16, error) {
+				return ff1.Decrypt(x, tweak)
+			})
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if got != tt.plaintext {
+				t.Errorf("Decrypt() = %v, want %v", got, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	store := testutil.NewSecretStore(t, "PIIKEY", 2)
+	tests := []struct {
+		name   string
+		value  string
+		domain Domain
+		opts   []Option
+	}{
+		{"Phone number", "+1 (555) 010-4477", Digits, nil},
+		{"Card number", "4111 1111 1111 1111", Digits, nil},
+		{"Alphanumeric", "AB12-cd34-EF56", Alphanumeric, nil},
+		{"Email", "jane.doe+news@example.com", EmailLocalPart, nil},
+		{"Tweak", "4111111111111111", Digits, []Option{WithTweak([]byte("card"))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithStore(store)}, tt.opts...)
+			ciphertext, version, err := Encrypt("PIIKEY", tt.value, tt.domain, opts...)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if version != 2 {
+				t.Errorf("Encrypt() version = %v, want %v", version, 2)
+			}
+			if ciphertext == tt.value {
+				t.Errorf("Encrypt() = %v, want a different value", ciphertext)
+			}
+			if !sameFormat(tt.domain, tt.value, ciphertext) {
+				t.Errorf("Encrypt() = %v, does not have the format of %v", ciphertext, tt.value)
+			}
+
+			got, err := Decrypt("PIIKEY", version, ciphertext, tt.domain, opts...)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if got != tt.value {
+				t.Errorf("Decrypt() = %v, want %v", got, tt.value)
+			}
+		})
+	}
+}
+
+// sameFormat reports whether a and b only differ in characters of the
+// domain's alphabet.
+func sameFormat(domain Domain, a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) != len(rb) {
+		return false
+	}
+	for i := range ra {
+		if ra[i] != rb[i] && !(domain.Alphabet.Contains(ra[i]) && domain.Alphabet.Contains(rb[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEncryptEmailKeepsDomain(t *testing.T) {
+	store := testutil.NewSecretStore(t, "PIIKEY", 1)
+	ciphertext, _, err := Encrypt("PIIKEY", "jane.doe@example.com", EmailLocalPart, WithStore(store))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if got, want := ciphertext[len(ciphertext)-len("@example.com"):], "@example.com"; got != want {
+		t.Errorf("Encrypt() domain = %v, want %v", got, want)
+	}
+	if ciphertext[4] != '.' {
+		t.Errorf("Encrypt() = %v, want the dot of the local part kept", ciphertext)
+	}
+}
+
+func TestEncryptErrors(t *testing.T) {
+	store := testutil.NewSecretStore(t, "PIIKEY", 1)
+	tests := []struct {
+		name    string
+		keyName string
+		value   string
+		domain  Domain
+		wantErr error
+	}{
+		{"Unknown key", "MISSING", "4111111111111111", Digits, errors.ErrKeyNotFound},
+		{"Too short", "PIIKEY", "12-34", Digits, errors.ErrInvalidData},
+		{"Not an email", "PIIKEY", "janedoe", EmailLocalPart, errors.ErrInvalidData},
+		{"Outside alphabet", "PIIKEY", "1234-5678", Domain{Alphabet: Base10}, errors.ErrInvalidData},
+		{"No alphabet", "PIIKEY", "12345678", Domain{}, errors.ErrInvalidData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Encrypt(tt.keyName, tt.value, tt.domain, WithStore(store))
+			if !stdErrors.Is(err, tt.wantErr) {
+				t.Errorf("Encrypt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecryptWrongTweak(t *testing.T) {
+	store := testutil.NewSecretStore(t, "PIIKEY", 1)
+	ciphertext, version, err := Encrypt("PIIKEY", "4111111111111111", Digits, WithStore(store), WithTweak([]byte("a")))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	got, err := Decrypt("PIIKEY", version, ciphertext, Digits, WithStore(store), WithTweak([]byte("b")))
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if got == "4111111111111111" {
+		t.Errorf("Decrypt() = %v, want a different value with another tweak", got)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	store := testutil.NewSecretStore(t, "PIIKEY", 1)
+	ciphertext, version, err := Encrypt("PIIKEY", "+1 (555) 010-4477", Digits, WithStore(store))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	if _, _, rotated, err := Rotate("PIIKEY", version, ciphertext, Digits, WithStore(store)); err != nil || rotated {
+		t.Fatalf("Rotate() = %v, %v, want no rotation", rotated, err)
+	}
+
+	if err := store.Put(models.KeyInfo{Name: "PIIKEY", Version: 2, Secret: bytes.Repeat([]byte{2}, 32)}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	rotated, newVersion, ok, err := Rotate("PIIKEY", version, ciphertext, Digits, WithStore(store))
+	if err != nil || !ok {
+		t.Fatalf("Rotate() = %v, %v, want a rotation", ok, err)
+	}
+	if newVersion != 2 {
+		t.Errorf("Rotate() version = %v, want %v", newVersion, 2)
+	}
+	got, err := Decrypt("PIIKEY", newVersion, rotated, Digits, WithStore(store))
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if got != "+1 (555) 010-4477" {
+		t.Errorf("Decrypt() = %v, want %v", got, "+1 (555) 010-4477")
+	}
+}
+
+func TestNewAlphabet(t *testing.T) {
+	tests := []struct {
+		name    string
+		chars   string
+		want    int
+		wantErr bool
+	}{
+		{"Hex", "0123456789abcdef", 16, false},
+		{"Unicode", "αβγδ", 4, false},
+		{"Duplicate", "0120", 0, true},
+		{"Single", "0", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAlphabet(tt.chars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAlphabet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && a.Radix() != tt.want {
+				t.Errorf("Radix() = %v, want %v", a.Radix(), tt.want)
+			}
+		})
+	}
+}
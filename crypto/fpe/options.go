@@ -0,0 +1,47 @@
+package fpe
+
+import (
+	"github.com/nected/go-lib/crypto/models"
+)
+
+type options struct {
+	store models.KeyStore
+	tweak []byte
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		store: models.DefaultSecretStore(),
+	}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	return o
+}
+
+type Option interface {
+	apply(*options)
+}
+
+// optionFunc wraps a func so it satisfies the Option interface.
+type optionFunc func(*options)
+
+func (f optionFunc) apply(o *options) {
+	f(o)
+}
+
+// WithStore looks FPE keys up in store instead of the default secret store.
+func WithStore(store models.KeyStore) Option {
+	return optionFunc(func(o *options) {
+		o.store = store
+	})
+}
+
+// WithTweak binds the ciphertext to tweak, e.g. the name of the field or the
+// id of the record, so that equal values of different fields encrypt
+// differently. The same tweak must be passed to Decrypt.
+func WithTweak(tweak []byte) Option {
+	return optionFunc(func(o *options) {
+		o.tweak = tweak
+	})
+}
@@ -0,0 +1,67 @@
+// Package testutil holds the helpers shared by the tests of the crypto
+// packages.
+package testutil
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// DecodeHex decodes the hex string s, ignoring spaces.
+func DecodeHex(t testing.TB, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatalf("hex.DecodeString(%q) error = %v", s, err)
+	}
+	return b
+}
+
+// NewKeyInfo returns version of the key name holding a fresh 2048-bit RSA
+// key pair.
+func NewKeyInfo(t testing.TB, name string, version int) models.KeyInfo {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return models.KeyInfo{
+		Name:    name,
+		Version: version,
+		PrivKey: privateKey,
+		PubKey:  &privateKey.PublicKey,
+	}
+}
+
+// NewKeyStore returns a store holding versions 1 to versions of the RSA key
+// name.
+func NewKeyStore(t testing.TB, name string, versions int) *models.MemoryKeyStore {
+	t.Helper()
+	store := models.NewMemoryKeyStore()
+	for version := 1; version <= versions; version++ {
+		if err := store.Put(NewKeyInfo(t, name, version)); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	return store
+}
+
+// NewSecretStore returns a store holding versions 1 to versions of the secret
+// name. The secret of each version is 32 bytes of the version number.
+func NewSecretStore(t testing.TB, name string, versions int) *models.MemoryKeyStore {
+	t.Helper()
+	store := models.NewMemoryKeyStore()
+	for version := 1; version <= versions; version++ {
+		secret := bytes.Repeat([]byte{byte(version)}, 32)
+		if err := store.Put(models.KeyInfo{Name: name, Version: version, Secret: secret}); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	return store
+}
@@ -0,0 +1,131 @@
+package jwk
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// KeyTypeRSA is the "kty" of RSA keys.
+const KeyTypeRSA = "RSA"
+
+// minRSAKeyBits is the smallest RSA modulus accepted from a key set.
+const minRSAKeyBits = 2048
+
+// Key is an RSA public key in JSON Web Key format (RFC 7517).
+type Key struct {
+	KeyType  string `json:"kty"`
+	KeyID    string `json:"kid"`
+	Use      string `json:"use,omitempty"`
+	Modulus  string `json:"n"`
+	Exponent string `json:"e"`
+}
+
+// KeySet is a JSON Web Key Set.
+type KeySet struct {
+	Keys []Key `json:"keys"`
+}
+
+// Export returns the public halves of every active key in store as a key
+// set. The "kid" of each key is its KeyNameVersion, the same value used by
+// crypto/jwt, so tokens can be verified against the exported set.
+func Export(store models.KeyStore) *KeySet {
+	set := &KeySet{Keys: make([]Key, 0)}
+	for _, keyInfo := range store.List() {
+		if !keyInfo.CanEncrypt() || keyInfo.GetPubKey() == nil {
+			continue
+		}
+		set.Keys = append(set.Keys, NewKey(keyInfo.KeyNameVersion(), keyInfo.GetPubKey()))
+	}
+	return set
+}
+
+// ExportJSON behaves like Export and serializes the key set.
+func ExportJSON(store models.KeyStore) ([]byte, error) {
+	return json.Marshal(Export(store))
+}
+
+// NewKey converts pubKey into a JSON Web Key identified by keyID.
+func NewKey(keyID string, pubKey *rsa.PublicKey) Key {
+	return Key{
+		KeyType:  KeyTypeRSA,
+		KeyID:    keyID,
+		Modulus:  base64.B64EncodeRawURL(pubKey.N.Bytes()),
+		Exponent: base64.B64EncodeRawURL(big.NewInt(int64(pubKey.E)).Bytes()),
+	}
+}
+
+// PublicKey decodes the RSA public key held by k. Moduli shorter than 2048
+// bits are rejected.
+func (k Key) PublicKey() (*rsa.PublicKey, error) {
+	if k.KeyType != KeyTypeRSA {
+		return nil, fmt.Errorf("%s: unsupported key type %q", k.KeyID, k.KeyType)
+	}
+	modulus, err := base64.B64DecodeRawURL(k.Modulus)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid modulus: %w", k.KeyID, err)
+	}
+	exponent, err := base64.B64DecodeRawURL(k.Exponent)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid exponent: %w", k.KeyID, err)
+	}
+	e := new(big.Int).SetBytes([]byte(exponent))
+	if modulus == "" || !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%s: invalid RSA public key", k.KeyID)
+	}
+	n := new(big.Int).SetBytes([]byte(modulus))
+	if n.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("%s: RSA key of %d bits, want at least %d", k.KeyID, n.BitLen(), minRSAKeyBits)
+	}
+	return &rsa.PublicKey{
+		N: n,
+		E: int(e.Int64()),
+	}, nil
+}
+
+// Import loads every key of the JSON encoded key set in data into store as a
+// public-only key. The key name and version are taken from the "kid", which
+// must be of the form <key_name>_<key_version>.
+//
+// Imported keys encrypt and verify signatures, but decrypting or signing with
+// them fails with errors.ErrNoPrivateKey. Keys of a type other than RSA are
+// skipped. Every key is checked before the first one is stored, so store is
+// left untouched when the key set holds an invalid key.
+func Import(store models.KeyStore, data []byte) error {
+	var set KeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+	imported := models.NewMemoryKeyStore()
+	for _, key := range set.Keys {
+		if key.KeyType != KeyTypeRSA {
+			continue
+		}
+		keyName, keyVersion, err := models.ParseKeyNameVersion(key.KeyID)
+		if err != nil {
+			return err
+		}
+		pubKey, err := key.PublicKey()
+		if err != nil {
+			return err
+		}
+		err = imported.Put(models.KeyInfo{
+			Name:    keyName,
+			Version: keyVersion,
+			PubKey:  pubKey,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, keyInfo := range imported.List() {
+		if err := store.Put(keyInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
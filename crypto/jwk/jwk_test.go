@@ -0,0 +1,137 @@
+package jwk
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	stdErrors "errors"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+	cryptoRSA "github.com/nected/go-lib/crypto/rsa"
+	"github.com/nected/go-lib/crypto/sign"
+)
+
+func TestExport(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	for _, keyInfo := range []models.KeyInfo{
+		testutil.NewKeyInfo(t, "JWKKEY", 1),
+		testutil.NewKeyInfo(t, "JWKKEY", 2),
+		testutil.NewKeyInfo(t, "OLDKEY", 1),
+	} {
+		if err := store.Put(keyInfo); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	if err := models.SetKeyState(store, "OLDKEY", 1, models.KeyStateDecryptOnly); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+
+	data, err := ExportJSON(store)
+	if err != nil {
+		t.Fatalf("ExportJSON() error = %v", err)
+	}
+	var set KeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := []string{"JWKKEY_1", "JWKKEY_2"}
+	if len(set.Keys) != len(want) {
+		t.Fatalf("ExportJSON() = %v keys, want %v", len(set.Keys), len(want))
+	}
+	for i, key := range set.Keys {
+		if key.KeyID != want[i] || key.KeyType != KeyTypeRSA || key.Exponent != "AQAB" {
+			t.Errorf("key %d = %+v, want kid %v", i, key, want[i])
+		}
+		pubKey, err := key.PublicKey()
+		if err != nil {
+			t.Fatalf("PublicKey() error = %v", err)
+		}
+		if !pubKey.Equal(store.Get("JWKKEY", i+1).GetPubKey()) {
+			t.Errorf("PublicKey() of %v does not match the stored key", key.KeyID)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	source := models.NewMemoryKeyStore()
+	if err := source.Put(testutil.NewKeyInfo(t, "JWKKEY", 3)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data, err := ExportJSON(source)
+	if err != nil {
+		t.Fatalf("ExportJSON() error = %v", err)
+	}
+
+	store := models.NewMemoryKeyStore()
+	if err := Import(store, data); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	keyInfo := store.Get("JWKKEY", 3)
+	if keyInfo == nil || keyInfo.HasPrivateKey() {
+		t.Fatalf("Get() = %v, want a public-only key", keyInfo)
+	}
+
+	// encrypt with the imported key, decrypt with the original one
+	payload, err := cryptoRSA.EncryptWithStore(store, "JWKKEY", []byte("data"))
+	if err != nil {
+		t.Fatalf("EncryptWithStore() error = %v", err)
+	}
+	got, err := cryptoRSA.DecryptWithStore(source, payload.String())
+	if err != nil || got.Data != "data" {
+		t.Errorf("DecryptWithStore() = %v, %v, want data", got, err)
+	}
+	if _, err := cryptoRSA.DecryptWithStore(store, payload.String()); !stdErrors.Is(err, errors.ErrNoPrivateKey) {
+		t.Errorf("DecryptWithStore() error = %v, want %v", err, errors.ErrNoPrivateKey)
+	}
+
+	// sign with the original key, verify with the imported one
+	signature, err := sign.SignWithStore(source, "JWKKEY", []byte("data"), sign.PSSWithSHA256)
+	if err != nil {
+		t.Fatalf("SignWithStore() error = %v", err)
+	}
+	if err := sign.VerifyWithStore(store, signature, []byte("data")); err != nil {
+		t.Errorf("VerifyWithStore() error = %v", err)
+	}
+	if _, err := sign.SignWithStore(store, "JWKKEY", []byte("data"), sign.PSSWithSHA256); !stdErrors.Is(err, errors.ErrNoPrivateKey) {
+		t.Errorf("SignWithStore() error = %v, want %v", err, errors.ErrNoPrivateKey)
+	}
+
+	shortKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	short, err := json.Marshal(NewKey("SHORTKEY_1", &shortKey.PublicKey))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	valid, err := json.Marshal(NewKey("VALIDKEY_1", keyInfo.GetPubKey()))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"Invalid JSON", `{"keys":`},
+		{"Invalid kid", `{"keys":[{"kty":"RSA","kid":"nokeyversion","n":"AQAB","e":"AQAB"}]}`},
+		{"Invalid modulus", `{"keys":[{"kty":"RSA","kid":"KEY_1","n":"!!","e":"AQAB"}]}`},
+		{"Short modulus", `{"keys":[` + string(short) + `]}`},
+		{"Valid key before a short one", `{"keys":[` + string(valid) + `,` + string(short) + `]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := models.NewMemoryKeyStore()
+			if err := Import(store, []byte(tt.data)); err == nil {
+				t.Errorf("Import() error = nil, want an error")
+			}
+			if keys := store.List(); len(keys) != 0 {
+				t.Errorf("List() = %v, want no key stored after a failed import", keys)
+			}
+		})
+	}
+}
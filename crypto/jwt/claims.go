@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Claims is implemented by every claim set Issue and Parse accept. Custom
+// claim sets embed RegisteredClaims to satisfy it:
+//
+//	type UserClaims struct {
+//		jwt.RegisteredClaims
+//		Email string `json:"email"`
+//	}
+type Claims interface {
+	GetRegisteredClaims() *RegisteredClaims
+}
+
+// RegisteredClaims are the registered claim names of RFC 7519 section 4.1.
+type RegisteredClaims struct {
+	Issuer    string       `json:"iss,omitempty"`
+	Subject   string       `json:"sub,omitempty"`
+	Audience  Audience     `json:"aud,omitempty"`
+	ExpiresAt *NumericDate `json:"exp,omitempty"`
+	NotBefore *NumericDate `json:"nbf,omitempty"`
+	IssuedAt  *NumericDate `json:"iat,omitempty"`
+	ID        string       `json:"jti,omitempty"`
+}
+
+func (c *RegisteredClaims) GetRegisteredClaims() *RegisteredClaims {
+	return c
+}
+
+// Audience is the "aud" claim. It is serialized as a single string when it
+// holds one value and accepts both forms when parsed.
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = Audience(multiple)
+	return nil
+}
+
+// Contains reports whether audience is one of the values of a.
+func (a Audience) Contains(audience string) bool {
+	for _, value := range a {
+		if value == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// NumericDate is a JSON number of seconds since the epoch, as used by the
+// "exp", "nbf" and "iat" claims.
+type NumericDate struct {
+	time.Time
+}
+
+// NewNumericDate truncates t to whole seconds.
+func NewNumericDate(t time.Time) *NumericDate {
+	return &NumericDate{t.Truncate(time.Second)}
+}
+
+func (d NumericDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Unix())
+}
+
+func (d *NumericDate) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return err
+	}
+	whole, frac := math.Modf(seconds)
+	d.Time = time.Unix(int64(whole), int64(frac*float64(time.Second)))
+	return nil
+}
@@ -0,0 +1,214 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// Algorithm is the JWS "alg" header of a token.
+type Algorithm string
+
+const (
+	// RS256 is RSASSA-PKCS1-v1_5 with SHA-256.
+	RS256 Algorithm = "RS256"
+	// PS256 is RSASSA-PSS with SHA-256.
+	PS256 Algorithm = "PS256"
+)
+
+type header struct {
+	Algorithm Algorithm `json:"alg"`
+	Type      string    `json:"typ,omitempty"`
+	KeyID     string    `json:"kid"`
+}
+
+// Issue signs claims with the latest active version of the specified RSA key
+// and returns the compact serialized token. The "kid" header is set to the
+// KeyNameVersion of the signing key so Parse can find it again.
+//
+// Parameters:
+//   - keyName: The name of the signing key to use.
+//   - claims: The claims to sign, e.g. *RegisteredClaims or a custom struct
+//     embedding it.
+//   - opts: WithAlgorithm and WithStore are honoured.
+//
+// Returns:
+//   - string: The signed token.
+//   - error: errors.ErrInvalidClaims if claims is nil, errors.ErrKeyNotFound
+//     if the key is not loaded, or an error if signing fails.
+func Issue(keyName string, claims Claims, opts ...Option) (string, error) {
+	if _, err := registeredClaims(claims); err != nil {
+		return "", err
+	}
+	o := newOptions(opts)
+	if o.algorithm != RS256 && o.algorithm != PS256 {
+		return "", fmt.Errorf("unsupported algorithm %q", o.algorithm)
+	}
+	keyInfo, err := models.FindKey(o.store, keyName, 0)
+	if err != nil {
+		return "", err
+	}
+	if keyInfo.GetAlgorithm() != models.KeyAlgorithmRSA {
+		return "", fmt.Errorf("%w: %s keys can not sign %s tokens", errors.ErrUnsupportedKeyAlgorithm, keyInfo.GetAlgorithm(), o.algorithm)
+	}
+	if !keyInfo.HasPrivateKey() {
+		return "", &errors.NoPrivateKeyError{KeyName: keyInfo.GetName(), KeyVersion: keyInfo.GetVersion()}
+	}
+
+	headerJSON, err := json.Marshal(header{Algorithm: o.algorithm, Type: "JWT", KeyID: keyInfo.KeyNameVersion()})
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.B64EncodeRawURL(headerJSON) + "." + base64.B64EncodeRawURL(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch o.algorithm {
+	case RS256:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, keyInfo.GetPrivKey(), crypto.SHA256, digest[:])
+	case PS256:
+		signature, err = rsa.SignPSS(rand.Reader, keyInfo.GetPrivKey(), crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	}
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.B64EncodeRawURL(signature), nil
+}
+
+// Parse verifies token and decodes its claims into claims.
+//
+// The signing key is looked up by the "kid" header; decrypt-only keys still
+// validate tokens, revoked keys do not. After the signature, the "exp", "nbf"
+// and "iat" claims are checked against the current time with the configured
+// leeway, and "aud" and "iss" when WithAudience or WithIssuer are given.
+//
+// Returns:
+//   - error: errors.ErrInvalidClaims if claims is nil,
+//     errors.ErrTokenMalformed, errors.ErrInvalidSignature,
+//     errors.ErrKeyNotFound, errors.ErrKeyRevoked or one of the claim errors,
+//     such as errors.ErrTokenExpired.
+func Parse(token string, claims Claims, opts ...Option) error {
+	if _, err := registeredClaims(claims); err != nil {
+		return err
+	}
+	o := newOptions(opts)
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.ErrTokenMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return err
+	}
+	if h.Algorithm != RS256 && h.Algorithm != PS256 {
+		return fmt.Errorf("%w: unsupported algorithm %q", errors.ErrTokenMalformed, h.Algorithm)
+	}
+	keyInfo, err := lookupKeyID(o.store, h.KeyID)
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.B64DecodeRawURL(parts[2])
+	if err != nil {
+		return errors.ErrTokenMalformed
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch h.Algorithm {
+	case RS256:
+		err = rsa.VerifyPKCS1v15(keyInfo.GetPubKey(), crypto.SHA256, digest[:], []byte(signature))
+	case PS256:
+		err = rsa.VerifyPSS(keyInfo.GetPubKey(), crypto.SHA256, digest[:], []byte(signature), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrInvalidSignature, err)
+	}
+
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return err
+	}
+	registered, err := registeredClaims(claims)
+	if err != nil {
+		return err
+	}
+	return o.validate(registered)
+}
+
+// registeredClaims returns the registered claims of claims, or
+// errors.ErrInvalidClaims when claims or its registered claims are nil.
+func registeredClaims(claims Claims) (*RegisteredClaims, error) {
+	if claims == nil {
+		return nil, errors.ErrInvalidClaims
+	}
+	if v := reflect.ValueOf(claims); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, errors.ErrInvalidClaims
+	}
+	registered := claims.GetRegisteredClaims()
+	if registered == nil {
+		return nil, errors.ErrInvalidClaims
+	}
+	return registered, nil
+}
+
+func (o *options) validate(claims *RegisteredClaims) error {
+	now := o.now()
+	if claims.ExpiresAt != nil && now.After(claims.ExpiresAt.Add(o.leeway)) {
+		return errors.ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(o.leeway).Before(claims.NotBefore.Time) {
+		return errors.ErrTokenNotValidYet
+	}
+	if claims.IssuedAt != nil && now.Add(o.leeway).Before(claims.IssuedAt.Time) {
+		return errors.ErrTokenUsedBeforeIssued
+	}
+	if o.audience != "" && !claims.Audience.Contains(o.audience) {
+		return errors.ErrTokenInvalidAudience
+	}
+	if o.issuer != "" && claims.Issuer != o.issuer {
+		return errors.ErrTokenInvalidIssuer
+	}
+	return nil
+}
+
+// lookupKeyID resolves a "kid" of the form <key_name>_<key_version>.
+func lookupKeyID(store models.KeyStore, keyID string) (*models.KeyInfo, error) {
+	keyName, keyVersion, err := models.ParseKeyNameVersion(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrTokenMalformed, err)
+	}
+
+	keyInfo, err := models.FindDecryptionKey(store, keyName, keyVersion)
+	if err != nil {
+		return nil, err
+	}
+	if keyInfo.GetAlgorithm() != models.KeyAlgorithmRSA {
+		return nil, fmt.Errorf("%w: %s", errors.ErrUnsupportedKeyAlgorithm, keyInfo.GetAlgorithm())
+	}
+	return keyInfo, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.B64DecodeRawURL(segment)
+	if err != nil {
+		return errors.ErrTokenMalformed
+	}
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrTokenMalformed, err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package jwt
+
+import (
+	"encoding/json"
+	stdErrors "errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+type userClaims struct {
+	RegisteredClaims
+	Email string `json:"email"`
+}
+
+func TestIssue(t *testing.T) {
+	store := testutil.NewKeyStore(t, "JWTKEY", 2)
+	now := time.Unix(1700000000, 0)
+
+	for _, algorithm := range []Algorithm{RS256, PS256} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			claims := &userClaims{
+				RegisteredClaims: RegisteredClaims{
+					Issuer:    "go-lib",
+					Subject:   "user-1",
+					Audience:  Audience{"api"},
+					IssuedAt:  NewNumericDate(now),
+					ExpiresAt: NewNumericDate(now.Add(time.Hour)),
+				},
+				Email: "user@example.com",
+			}
+			token, err := Issue("JWTKEY", claims, WithStore(store), WithAlgorithm(algorithm))
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+
+			var h header
+			if err := decodeSegment(strings.Split(token, ".")[0], &h); err != nil {
+				t.Fatalf("decodeSegment() error = %v", err)
+			}
+			if h.Algorithm != algorithm || h.KeyID != "JWTKEY_2" {
+				t.Errorf("header = %+v, want alg %v and kid JWTKEY_2", h, algorithm)
+			}
+
+			got := &userClaims{}
+			err = Parse(token, got, WithStore(store), WithClock(func() time.Time { return now }),
+				WithAudience("api"), WithIssuer("go-lib"))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got.Email != claims.Email || got.Subject != claims.Subject || !got.ExpiresAt.Equal(claims.ExpiresAt.Time) {
+				t.Errorf("Parse() = %+v, want %+v", got, claims)
+			}
+		})
+	}
+
+	if _, err := Issue("MISSING", &RegisteredClaims{}, WithStore(store)); !stdErrors.Is(err, errors.ErrKeyNotFound) {
+		t.Errorf("Issue() error = %v, want %v", err, errors.ErrKeyNotFound)
+	}
+	if _, err := Issue("JWTKEY", &RegisteredClaims{}, WithStore(store), WithAlgorithm("HS256")); err == nil {
+		t.Errorf("Issue() error = nil, want an unsupported algorithm error")
+	}
+	for _, claims := range []Claims{nil, (*userClaims)(nil), (*RegisteredClaims)(nil)} {
+		if _, err := Issue("JWTKEY", claims, WithStore(store)); !stdErrors.Is(err, errors.ErrInvalidClaims) {
+			t.Errorf("Issue(%#v) error = %v, want %v", claims, err, errors.ErrInvalidClaims)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	store := testutil.NewKeyStore(t, "JWTKEY", 1)
+	now := time.Unix(1700000000, 0)
+	clock := WithClock(func() time.Time { return now })
+
+	issue := func(claims RegisteredClaims) string {
+		token, err := Issue("JWTKEY", &claims, WithStore(store))
+		if err != nil {
+			t.Fatalf("Issue() error = %v", err)
+		}
+		return token
+	}
+	valid := issue(RegisteredClaims{Issuer: "go-lib", Audience: Audience{"api", "web"}, ExpiresAt: NewNumericDate(now.Add(time.Minute))})
+
+	parts := strings.Split(valid, ".")
+	noneHeader, _ := json.Marshal(header{Algorithm: "none", KeyID: "JWTKEY_1"})
+	unknownKid, _ := json.Marshal(header{Algorithm: RS256, KeyID: "JWTKEY_9"})
+	otherClaims, _ := json.Marshal(RegisteredClaims{Issuer: "attacker"})
+
+	tests := []struct {
+		name    string
+		token   string
+		opts    []Option
+		wantErr error
+	}{
+		{"Valid", valid, []Option{WithAudience("web"), WithIssuer("go-lib")}, nil},
+		{"Malformed", "not.a-token", nil, errors.ErrTokenMalformed},
+		{"Alg none", base64.B64EncodeRawURL(noneHeader) + "." + parts[1] + ".", nil, errors.ErrTokenMalformed},
+		{"Unknown kid", base64.B64EncodeRawURL(unknownKid) + "." + parts[1] + "." + parts[2], nil, errors.ErrKeyNotFound},
+		{"Tampered claims", parts[0] + "." + base64.B64EncodeRawURL(otherClaims) + "." + parts[2], nil, errors.ErrInvalidSignature},
+		{"Expired", issue(RegisteredClaims{ExpiresAt: NewNumericDate(now.Add(-time.Minute))}), nil, errors.ErrTokenExpired},
+		{"Expired within leeway", issue(RegisteredClaims{ExpiresAt: NewNumericDate(now.Add(-time.Minute))}), []Option{WithLeeway(2 * time.Minute)}, nil},
+		{"Not valid yet", issue(RegisteredClaims{NotBefore: NewNumericDate(now.Add(time.Minute))}), nil, errors.ErrTokenNotValidYet},
+		{"Issued in the future", issue(RegisteredClaims{IssuedAt: NewNumericDate(now.Add(time.Minute))}), nil, errors.ErrTokenUsedBeforeIssued},
+		{"Wrong audience", valid, []Option{WithAudience("admin")}, errors.ErrTokenInvalidAudience},
+		{"Wrong issuer", valid, []Option{WithIssuer("other")}, errors.ErrTokenInvalidIssuer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithStore(store), clock}, tt.opts...)
+			if err := Parse(tt.token, &RegisteredClaims{}, opts...); !stdErrors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := models.SetKeyState(store, "JWTKEY", 1, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if err := Parse(valid, &RegisteredClaims{}, WithStore(store), clock); !stdErrors.Is(err, errors.ErrKeyRevoked) {
+		t.Errorf("Parse() error = %v, want %v", err, errors.ErrKeyRevoked)
+	}
+	for _, claims := range []Claims{nil, (*userClaims)(nil), (*RegisteredClaims)(nil)} {
+		if err := Parse(valid, claims, WithStore(store), clock); !stdErrors.Is(err, errors.ErrInvalidClaims) {
+			t.Errorf("Parse(%#v) error = %v, want %v", claims, err, errors.ErrInvalidClaims)
+		}
+	}
+}
+
+func TestAudience(t *testing.T) {
+	tests := []struct {
+		name string
+		aud  Audience
+		want string
+	}{
+		{"Single", Audience{"api"}, `"api"`},
+		{"Multiple", Audience{"api", "web"}, `["api","web"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.aud)
+			if err != nil || string(data) != tt.want {
+				t.Fatalf("Marshal() = %s, %v, want %s", data, err, tt.want)
+			}
+			var got Audience
+			if err := json.Unmarshal(data, &got); err != nil || len(got) != len(tt.aud) {
+				t.Errorf("Unmarshal() = %v, %v, want %v", got, err, tt.aud)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+type options struct {
+	algorithm Algorithm
+	store     models.KeyStore
+	now       func() time.Time
+	leeway    time.Duration
+	audience  string
+	issuer    string
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		algorithm: RS256,
+		store:     models.DefaultKeyStore(),
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	return o
+}
+
+type Option interface {
+	apply(*options)
+}
+
+// optionFunc wraps a func so it satisfies the Option interface.
+type optionFunc func(*options)
+
+func (f optionFunc) apply(o *options) {
+	f(o)
+}
+
+// WithAlgorithm sets the signing algorithm used by Issue. The default is
+// RS256.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return optionFunc(func(o *options) {
+		o.algorithm = algorithm
+	})
+}
+
+// WithStore looks keys up in store instead of the default store.
+func WithStore(store models.KeyStore) Option {
+	return optionFunc(func(o *options) {
+		o.store = store
+	})
+}
+
+// WithClock replaces time.Now as the source of the current time.
+func WithClock(now func() time.Time) Option {
+	return optionFunc(func(o *options) {
+		o.now = now
+	})
+}
+
+// WithLeeway allows for clock skew between the issuer and Parse when checking
+// the "exp", "nbf" and "iat" claims.
+func WithLeeway(leeway time.Duration) Option {
+	return optionFunc(func(o *options) {
+		o.leeway = leeway
+	})
+}
+
+// WithAudience makes Parse require audience in the "aud" claim.
+func WithAudience(audience string) Option {
+	return optionFunc(func(o *options) {
+		o.audience = audience
+	})
+}
+
+// WithIssuer makes Parse require issuer as the "iss" claim.
+func WithIssuer(issuer string) Option {
+	return optionFunc(func(o *options) {
+		o.issuer = issuer
+	})
+}
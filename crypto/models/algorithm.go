@@ -0,0 +1,183 @@
+package models
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"io"
+
+	"github.com/nected/go-lib/crypto/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeyAlgorithm is the algorithm of a key pair.
+type KeyAlgorithm string
+
+const (
+	KeyAlgorithmRSA KeyAlgorithm = "RSA"
+	// KeyAlgorithmECDSA keys sign with ECDSA and encrypt with ECIES.
+	KeyAlgorithmECDSA KeyAlgorithm = "ECDSA"
+	// KeyAlgorithmEd25519 keys only sign.
+	KeyAlgorithmEd25519 KeyAlgorithm = "ED25519"
+	// KeyAlgorithmX25519 keys only encrypt, with ECIES over X25519.
+	KeyAlgorithmX25519 KeyAlgorithm = "X25519"
+)
+
+// eciesTagSize is the size of the AES-GCM tag appended by eciesEncrypt.
+const eciesTagSize = 16
+
+func (k *KeyInfo) GetAlgorithm() KeyAlgorithm {
+	if k.Algorithm == "" {
+		return KeyAlgorithmRSA
+	}
+	return k.Algorithm
+}
+
+func (k *KeyInfo) SetAlgorithm(algorithm KeyAlgorithm) {
+	k.Algorithm = algorithm
+}
+
+// GetPrivateKey returns the private key of any algorithm, or nil if the key
+// only holds a public key.
+func (k *KeyInfo) GetPrivateKey() crypto.PrivateKey {
+	if k.GetAlgorithm() == KeyAlgorithmRSA {
+		// never wrap a nil *rsa.PrivateKey in a non-nil interface
+		if k.PrivKey == nil {
+			return nil
+		}
+		return k.PrivKey
+	}
+	return k.PrivateKey
+}
+
+// GetPublicKey returns the public key of any algorithm.
+func (k *KeyInfo) GetPublicKey() crypto.PublicKey {
+	if k.GetAlgorithm() == KeyAlgorithmRSA {
+		if k.PubKey == nil {
+			return nil
+		}
+		return k.PubKey
+	}
+	return k.PublicKey
+}
+
+// CiphertextSize returns the length of the output of Encrypt for a plaintext
+// of plaintextSize bytes. It fails for keys that can not encrypt, such as RSA
+// keys too small for OAEP with SHA-512.
+func (k *KeyInfo) CiphertextSize(plaintextSize int) (int, error) {
+	switch k.GetAlgorithm() {
+	case KeyAlgorithmRSA:
+		step, err := k.rsaStep()
+		if err != nil {
+			return 0, err
+		}
+		blocks := (plaintextSize + step - 1) / step
+		return blocks * k.PubKey.Size(), nil
+	case KeyAlgorithmECDSA, KeyAlgorithmX25519:
+		publicKey, err := k.ecdhPublicKey()
+		if err != nil {
+			return 0, err
+		}
+		return len(publicKey.Bytes()) + plaintextSize + eciesTagSize, nil
+	}
+	return 0, fmt.Errorf("%w: %s keys can not encrypt", errors.ErrUnsupportedKeyAlgorithm, k.GetAlgorithm())
+}
+
+// rsaStep returns the size of the plaintext chunks RSA-OAEP with SHA-512
+// encrypts with the key. Keys too small to hold a chunk are invalid.
+func (k *KeyInfo) rsaStep() (int, error) {
+	step := k.PubKey.Size() - 2*sha512.Size - 2
+	if step <= 0 {
+		return 0, fmt.Errorf("%w: RSA key of %d bits is too small", errors.ErrInvalidKeyInfo, k.PubKey.N.BitLen())
+	}
+	return step, nil
+}
+
+func (k *KeyInfo) ecdhPublicKey() (*ecdh.PublicKey, error) {
+	switch publicKey := k.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return publicKey.ECDH()
+	case *ecdh.PublicKey:
+		return publicKey, nil
+	}
+	return nil, fmt.Errorf("%w: %T", errors.ErrUnsupportedKeyAlgorithm, k.PublicKey)
+}
+
+func (k *KeyInfo) ecdhPrivateKey() (*ecdh.PrivateKey, error) {
+	switch privateKey := k.PrivateKey.(type) {
+	case *ecdsa.PrivateKey:
+		return privateKey.ECDH()
+	case *ecdh.PrivateKey:
+		return privateKey, nil
+	}
+	return nil, fmt.Errorf("%w: %T", errors.ErrUnsupportedKeyAlgorithm, k.PrivateKey)
+}
+
+// eciesEncrypt encrypts data to publicKey and returns
+// ephemeralPublicKey||ciphertext||tag.
+//
+// A fresh ephemeral key pair is generated for every message. The AES-256-GCM
+// key is derived from the shared secret with HKDF-SHA256, salted with both
+// public keys and bound to label, so a key is never reused and a zero nonce
+// is safe.
+func eciesEncrypt(publicKey *ecdh.PublicKey, data, label []byte) ([]byte, error) {
+	ephemeralKey, err := publicKey.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := ephemeralKey.ECDH(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := eciesGCM(sharedSecret, ephemeralKey.PublicKey(), publicKey, label)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	return gcm.Seal(ephemeralKey.PublicKey().Bytes(), nonce, data, nil), nil
+}
+
+// eciesDecrypt reverses eciesEncrypt.
+func eciesDecrypt(privateKey *ecdh.PrivateKey, data, label []byte) ([]byte, error) {
+	ephemeralSize := len(privateKey.PublicKey().Bytes())
+	if len(data) < ephemeralSize+eciesTagSize {
+		return nil, errors.ErrTruncatedData
+	}
+	ephemeralPublicKey, err := privateKey.Curve().NewPublicKey(data[:ephemeralSize])
+	if err != nil {
+		return nil, errors.ErrInvalidData
+	}
+	sharedSecret, err := privateKey.ECDH(ephemeralPublicKey)
+	if err != nil {
+		return nil, errors.ErrInvalidData
+	}
+	gcm, err := eciesGCM(sharedSecret, ephemeralPublicKey, privateKey.PublicKey(), label)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	decryptedData, err := gcm.Open(nil, nonce, data[ephemeralSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrAuthenticationFailed, err)
+	}
+	return decryptedData, nil
+}
+
+func eciesGCM(sharedSecret []byte, ephemeralKey, recipientKey *ecdh.PublicKey, label []byte) (cipher.AEAD, error) {
+	salt := append(ephemeralKey.Bytes(), recipientKey.Bytes()...)
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, salt, label), key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	stdErrors "errors"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+)
+
+func TestKeyInfoECIES(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	xKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		keyInfo KeyInfo
+	}{
+		{"ECDSA", KeyInfo{Name: "ECKEY", Version: 1, Algorithm: KeyAlgorithmECDSA, PrivateKey: ecKey, PublicKey: &ecKey.PublicKey}},
+		{"X25519", KeyInfo{Name: "XKEY", Version: 1, Algorithm: KeyAlgorithmX25519, PrivateKey: xKey, PublicKey: xKey.PublicKey()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte("hello ecies")
+			encrypted, err := tt.keyInfo.EncryptWithAAD(data, []byte("context"))
+			if err != nil {
+				t.Fatalf("EncryptWithAAD() error = %v", err)
+			}
+			if size, err := tt.keyInfo.CiphertextSize(len(data)); err != nil || size != len(encrypted) {
+				t.Errorf("CiphertextSize() = %d, %v, want %d", size, err, len(encrypted))
+			}
+			decrypted, err := tt.keyInfo.DecryptWithAAD(encrypted, []byte("context"))
+			if err != nil {
+				t.Fatalf("DecryptWithAAD() error = %v", err)
+			}
+			if !bytes.Equal(decrypted, data) {
+				t.Errorf("DecryptWithAAD() = %q, want %q", decrypted, data)
+			}
+
+			if _, err := tt.keyInfo.DecryptWithAAD(encrypted, []byte("other")); err == nil {
+				t.Errorf("DecryptWithAAD() error = nil, want an error for the wrong additional data")
+			}
+			tampered := bytes.Clone(encrypted)
+			tampered[len(tampered)-1] ^= 1
+			if _, err := tt.keyInfo.DecryptWithAAD(tampered, []byte("context")); err == nil {
+				t.Errorf("DecryptWithAAD() error = nil, want an error for tampered data")
+			}
+
+			publicOnly := tt.keyInfo
+			publicOnly.PrivateKey = nil
+			if _, err := publicOnly.EncryptWithAAD(data, nil); err != nil {
+				t.Errorf("EncryptWithAAD() error = %v, want public keys to encrypt", err)
+			}
+			if _, err := publicOnly.DecryptWithAAD(encrypted, []byte("context")); !stdErrors.Is(err, errors.ErrNoPrivateKey) {
+				t.Errorf("DecryptWithAAD() error = %v, want %v", err, errors.ErrNoPrivateKey)
+			}
+		})
+	}
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edKeyInfo := KeyInfo{Name: "EDKEY", Version: 1, Algorithm: KeyAlgorithmEd25519, PrivateKey: edKey, PublicKey: edKey.Public()}
+	if _, err := edKeyInfo.Encrypt([]byte("data")); !stdErrors.Is(err, errors.ErrUnsupportedKeyAlgorithm) {
+		t.Errorf("Encrypt() error = %v, want %v", err, errors.ErrUnsupportedKeyAlgorithm)
+	}
+}
+
+func TestKeyInfoShortRSAKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	keyInfo := KeyInfo{Name: "SHORTKEY", Version: 1, PrivKey: privateKey, PubKey: &privateKey.PublicKey}
+	if _, err := keyInfo.Encrypt([]byte("data")); !stdErrors.Is(err, errors.ErrInvalidKeyInfo) {
+		t.Errorf("Encrypt() error = %v, want %v", err, errors.ErrInvalidKeyInfo)
+	}
+	if _, err := keyInfo.CiphertextSize(32); !stdErrors.Is(err, errors.ErrInvalidKeyInfo) {
+		t.Errorf("CiphertextSize() error = %v, want %v", err, errors.ErrInvalidKeyInfo)
+	}
+}
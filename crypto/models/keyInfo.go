@@ -1,23 +1,75 @@
 package models
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha512"
 	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nected/go-lib/crypto/errors"
 )
 
 type EncryptStruct struct {
 	AvailableKeys map[string]map[int]KeyInfo
 }
 
-var encryptKeysMap *EncryptStruct
+// KeyState controls which operations a key version may be used for.
+type KeyState int
+
+const (
+	// KeyStateActive keys encrypt and decrypt. It is the zero value.
+	KeyStateActive KeyState = iota
+	// KeyStateDecryptOnly keys still decrypt existing payloads but are never
+	// picked for new encryptions.
+	KeyStateDecryptOnly
+	// KeyStateRevoked keys neither encrypt nor decrypt.
+	KeyStateRevoked
+)
+
+func (s KeyState) String() string {
+	switch s {
+	case KeyStateActive:
+		return "ACTIVE"
+	case KeyStateDecryptOnly:
+		return "DECRYPTONLY"
+	case KeyStateRevoked:
+		return "REVOKED"
+	}
+	return fmt.Sprintf("KeyState(%d)", int(s))
+}
+
+// ParseKeyState parses the case insensitive name of a key state as returned
+// by KeyState.String.
+func ParseKeyState(state string) (KeyState, error) {
+	switch strings.ToUpper(state) {
+	case "ACTIVE":
+		return KeyStateActive, nil
+	case "DECRYPTONLY":
+		return KeyStateDecryptOnly, nil
+	case "REVOKED":
+		return KeyStateRevoked, nil
+	}
+	return KeyStateActive, fmt.Errorf("invalid key state %q", state)
+}
 
 type KeyInfo struct {
 	PrivKey *rsa.PrivateKey
 	PubKey  *rsa.PublicKey
-	Name    string
-	Version int
+	// Algorithm is the algorithm of the key pair. The zero value means RSA,
+	// whose keys are held by PrivKey and PubKey; keys of every other
+	// algorithm are held by PrivateKey and PublicKey.
+	Algorithm  KeyAlgorithm
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+	Name       string
+	Version    int
+	State      KeyState
+	// Secret is the AES key of a named secret. It is only set on keys held by
+	// the secret store; RSA keys leave it empty.
+	Secret []byte
 }
 
 func (k *KeyInfo) GetPrivKey() *rsa.PrivateKey {
@@ -52,21 +104,91 @@ func (k *KeyInfo) SetVersion(version int) {
 	k.Version = version
 }
 
+func (k *KeyInfo) GetState() KeyState {
+	return k.State
+}
+
+func (k *KeyInfo) SetState(state KeyState) {
+	k.State = state
+}
+
+func (k *KeyInfo) GetSecret() []byte {
+	return k.Secret
+}
+
+func (k *KeyInfo) SetSecret(secret []byte) {
+	k.Secret = secret
+}
+
+// CanEncrypt reports whether the key may be used for new encryptions.
+func (k *KeyInfo) CanEncrypt() bool {
+	return k.State == KeyStateActive
+}
+
+// CanDecrypt reports whether the key may be used to decrypt existing payloads.
+func (k *KeyInfo) CanDecrypt() bool {
+	return k.State == KeyStateActive || k.State == KeyStateDecryptOnly
+}
+
 func (k *KeyInfo) KeyNameVersion() string {
 	return fmt.Sprintf("%s_%v", k.GetName(), k.GetVersion())
 }
 
+// ParseKeyNameVersion reverses KeyInfo.KeyNameVersion.
+func ParseKeyNameVersion(keyNameVersion string) (string, int, error) {
+	idx := strings.LastIndex(keyNameVersion, "_")
+	if idx <= 0 {
+		return "", 0, fmt.Errorf("invalid key id %q", keyNameVersion)
+	}
+	keyVersion, err := strconv.Atoi(keyNameVersion[idx+1:])
+	if err != nil || keyVersion < 1 {
+		return "", 0, fmt.Errorf("invalid key id %q", keyNameVersion)
+	}
+	return keyNameVersion[:idx], keyVersion, nil
+}
+
+// HasPrivateKey reports whether the key can decrypt and sign. Keys imported
+// from a public key set only encrypt and verify.
+func (k *KeyInfo) HasPrivateKey() bool {
+	return k.GetPrivateKey() != nil
+}
+
 func (k *KeyInfo) Encrypt(data []byte) ([]byte, error) {
+	return k.EncryptWithAAD(data, nil)
+}
+
+// EncryptWithAAD encrypts data with RSA-OAEP, or ECIES for ECDSA and X25519
+// keys, binding it to additionalData through Label. The same additionalData
+// must be passed to DecryptWithAAD. Ed25519 keys only sign.
+func (k *KeyInfo) EncryptWithAAD(data, additionalData []byte) ([]byte, error) {
+	switch k.GetAlgorithm() {
+	case KeyAlgorithmRSA:
+		return k.encryptRSA(data, additionalData)
+	case KeyAlgorithmECDSA, KeyAlgorithmX25519:
+		publicKey, err := k.ecdhPublicKey()
+		if err != nil {
+			return nil, err
+		}
+		return eciesEncrypt(publicKey, data, k.Label(additionalData))
+	}
+	return nil, fmt.Errorf("%w: %s keys can not encrypt", errors.ErrUnsupportedKeyAlgorithm, k.GetAlgorithm())
+}
+
+func (k *KeyInfo) encryptRSA(data, additionalData []byte) ([]byte, error) {
 	msgLen := len(data)
 	encryptHash := sha512.New()
-	step := k.PubKey.Size() - 2*encryptHash.Size() - 2
+	step, err := k.rsaStep()
+	if err != nil {
+		return nil, err
+	}
+	label := k.Label(additionalData)
 	encryptedData := make([]byte, 0)
 	for i := 0; i < msgLen; i += step {
 		end := i + step
 		if end > msgLen {
 			end = msgLen
 		}
-		encrypted, err := rsa.EncryptOAEP(encryptHash, rand.Reader, k.GetPubKey(), data[i:end], []byte(k.KeyNameVersion()))
+		encrypted, err := rsa.EncryptOAEP(encryptHash, rand.Reader, k.GetPubKey(), data[i:end], label)
 		if err != nil {
 			return nil, err
 		}
@@ -76,54 +198,117 @@ func (k *KeyInfo) Encrypt(data []byte) ([]byte, error) {
 }
 
 func (k *KeyInfo) Decrypt(data []byte) ([]byte, error) {
+	return k.DecryptWithAAD(data, nil)
+}
+
+// DecryptWithAAD reverses EncryptWithAAD. It returns
+// errors.ErrAuthenticationFailed when data was encrypted with another key or
+// other additionalData.
+func (k *KeyInfo) DecryptWithAAD(data, additionalData []byte) ([]byte, error) {
+	if !k.HasPrivateKey() {
+		return nil, &errors.NoPrivateKeyError{KeyName: k.GetName(), KeyVersion: k.GetVersion()}
+	}
+	switch k.GetAlgorithm() {
+	case KeyAlgorithmRSA:
+		return k.decryptRSA(data, additionalData)
+	case KeyAlgorithmECDSA, KeyAlgorithmX25519:
+		privateKey, err := k.ecdhPrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		return eciesDecrypt(privateKey, data, k.Label(additionalData))
+	}
+	return nil, fmt.Errorf("%w: %s keys can not decrypt", errors.ErrUnsupportedKeyAlgorithm, k.GetAlgorithm())
+}
+
+func (k *KeyInfo) decryptRSA(data, additionalData []byte) ([]byte, error) {
 	msgLen := len(data)
 	decryptHash := sha512.New()
 	step := k.PubKey.Size()
+	if msgLen%step != 0 {
+		return nil, errors.ErrTruncatedData
+	}
+	label := k.Label(additionalData)
 	decryptedData := make([]byte, 0)
 	for i := 0; i < msgLen; i += step {
 		end := i + step
 		if end > msgLen {
 			end = msgLen
 		}
-		decrypted, err := rsa.DecryptOAEP(decryptHash, rand.Reader, k.GetPrivKey(), data[i:end], []byte(k.KeyNameVersion()))
+		decrypted, err := rsa.DecryptOAEP(decryptHash, rand.Reader, k.GetPrivKey(), data[i:end], label)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: %v", errors.ErrAuthenticationFailed, err)
 		}
 		decryptedData = append(decryptedData, decrypted...)
 	}
 	return decryptedData, nil
 }
 
+// Label returns the context a ciphertext of this key is bound to: the key name
+// and version, followed by additionalData when present.
+func (k *KeyInfo) Label(additionalData []byte) []byte {
+	label := []byte(k.KeyNameVersion())
+	if len(additionalData) > 0 {
+		// the separator can not be part of a key name, so a label never
+		// matches one built from another key and additionalData
+		label = append(append(label, 0), additionalData...)
+	}
+	return label
+}
+
+// GetEncryptKeysMap returns a snapshot of the keys held by the default store.
+// The snapshot is a copy: changing its maps does not change the store, use
+// ReplaceEncryptKeysMap or the KeyStore methods for that.
 func GetEncryptKeysMap() *EncryptStruct {
-	return encryptKeysMap
+	info := &EncryptStruct{
+		AvailableKeys: make(map[string]map[int]KeyInfo),
+	}
+	for _, keyInfo := range DefaultKeyStore().List() {
+		if _, ok := info.AvailableKeys[keyInfo.GetName()]; !ok {
+			info.AvailableKeys[keyInfo.GetName()] = make(map[int]KeyInfo)
+		}
+		info.AvailableKeys[keyInfo.GetName()][keyInfo.GetVersion()] = keyInfo
+	}
+	return info
 }
 
+// SetEncryptKeysMap replaces the default store with an in-memory store holding
+// a copy of the keys in info. It keeps the current default store if info holds
+// an invalid key; use ReplaceEncryptKeysMap to get the error.
 func SetEncryptKeysMap(info *EncryptStruct) {
-	encryptKeysMap = info
+	_ = ReplaceEncryptKeysMap(info)
 }
 
-func GetEncryptionKey(keyName string, version int) *KeyInfo {
-	info := GetEncryptKeysMap()
-	if info == nil {
-		return nil
-	}
-	keyInfoVersionMap, ok := info.AvailableKeys[keyName]
-	if !ok {
-		return nil
-	}
-	if version > 0 {
-		keyInfo := keyInfoVersionMap[version]
-		return &keyInfo
-	}
-	var latestKeyInfo *KeyInfo
-	for _, keyInfo := range keyInfoVersionMap {
-		if latestKeyInfo == nil {
-			latestKeyInfo = &keyInfo
-		}
-
-		if keyInfo.GetVersion() > latestKeyInfo.GetVersion() {
-			latestKeyInfo = &keyInfo
+// ReplaceEncryptKeysMap replaces the default store with an in-memory store
+// holding a copy of the keys in info, so later changes to info are not seen by
+// the store. A key without a name or version takes the one of its map entry.
+//
+// It returns errors.ErrInvalidKeyInfo, and keeps the current default store,
+// if a key is invalid or its name or version differs from its map entry.
+func ReplaceEncryptKeysMap(info *EncryptStruct) error {
+	store := NewMemoryKeyStore()
+	if info != nil {
+		for keyName, versions := range info.AvailableKeys {
+			for version, keyInfo := range versions {
+				if keyInfo.GetName() == "" {
+					keyInfo.SetName(keyName)
+				}
+				if keyInfo.GetVersion() == 0 {
+					keyInfo.SetVersion(version)
+				}
+				if keyInfo.GetName() != keyName || keyInfo.GetVersion() != version {
+					return fmt.Errorf("%w: %s stored as %s_%d", errors.ErrInvalidKeyInfo, keyInfo.KeyNameVersion(), keyName, version)
+				}
+				if err := store.Put(keyInfo); err != nil {
+					return fmt.Errorf("%s_%d: %w", keyName, version, err)
+				}
+			}
 		}
 	}
-	return latestKeyInfo
+	SetDefaultKeyStore(store)
+	return nil
+}
+
+func GetEncryptionKey(keyName string, version int) *KeyInfo {
+	return LookupKey(DefaultKeyStore(), keyName, version)
 }
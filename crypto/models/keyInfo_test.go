@@ -0,0 +1,56 @@
+package models
+
+import (
+	stdErrors "errors"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+)
+
+func TestSetEncryptKeysMap(t *testing.T) {
+	previous := DefaultKeyStore()
+	defer SetDefaultKeyStore(previous)
+
+	info := &EncryptStruct{AvailableKeys: map[string]map[int]KeyInfo{
+		"MAPKEY": {
+			1: {Name: "MAPKEY", Version: 1, Secret: []byte("secret")},
+			2: {},
+		},
+	}}
+	SetEncryptKeysMap(info)
+	if keyInfo := DefaultKeyStore().Get("MAPKEY", 2); keyInfo == nil {
+		t.Errorf("Get() = nil, want the key named after its map entry")
+	}
+
+	// the store holds a copy of info
+	delete(info.AvailableKeys, "MAPKEY")
+	if DefaultKeyStore().Get("MAPKEY", 1) == nil {
+		t.Errorf("Get() = nil, want the key to survive changes to info")
+	}
+	snapshot := GetEncryptKeysMap()
+	delete(snapshot.AvailableKeys["MAPKEY"], 1)
+	if DefaultKeyStore().Get("MAPKEY", 1) == nil {
+		t.Errorf("Get() = nil, want the key to survive changes to the snapshot")
+	}
+
+	tests := []struct {
+		name string
+		keys map[string]map[int]KeyInfo
+	}{
+		{"Version mismatch", map[string]map[int]KeyInfo{"MAPKEY": {3: {Name: "MAPKEY", Version: 4}}}},
+		{"Name mismatch", map[string]map[int]KeyInfo{"MAPKEY": {3: {Name: "OTHERKEY", Version: 3}}}},
+		{"Invalid version", map[string]map[int]KeyInfo{"MAPKEY": {-1: {}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ReplaceEncryptKeysMap(&EncryptStruct{AvailableKeys: tt.keys})
+			if !stdErrors.Is(err, errors.ErrInvalidKeyInfo) {
+				t.Errorf("ReplaceEncryptKeysMap() error = %v, want %v", err, errors.ErrInvalidKeyInfo)
+			}
+			SetEncryptKeysMap(&EncryptStruct{AvailableKeys: tt.keys})
+			if DefaultKeyStore().Get("MAPKEY", 1) == nil {
+				t.Errorf("Get() = nil, want the default store kept after a failure")
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nected/go-lib/crypto/errors"
+)
+
+// KeyStore holds versioned encryption keys addressed by name.
+//
+// Implementations return copies of the stored KeyInfo so callers can never
+// mutate the store through a returned value.
+type KeyStore interface {
+	// Get returns the given version of keyName, or nil if it is not present.
+	Get(keyName string, version int) *KeyInfo
+	// Latest returns the highest active version of keyName, or nil if there is none.
+	Latest(keyName string) *KeyInfo
+	// Put adds keyInfo to the store, replacing any key with the same name and version.
+	Put(keyInfo KeyInfo) error
+	// Delete removes the given version of keyName. A version of 0 removes every version.
+	Delete(keyName string, version int)
+	// List returns every stored key ordered by name and version.
+	List() []KeyInfo
+}
+
+// ReplaceableKeyStore is a KeyStore whose whole key set can be swapped in a
+// single step.
+type ReplaceableKeyStore interface {
+	KeyStore
+	// Replace discards every stored key and stores keys instead. Readers see
+	// either the old or the new key set, never a mix of both.
+	Replace(keys []KeyInfo) error
+}
+
+// StateSetter is implemented by stores that can change the state of a key
+// version atomically. SetKeyState uses it when available.
+type StateSetter interface {
+	// SetState changes the state of the given version of keyName, failing
+	// with a *errors.KeyError when it is not stored.
+	SetState(keyName string, version int, state KeyState) error
+}
+
+// MemoryKeyStore is a KeyStore backed by an in-process map. It is safe for
+// concurrent use.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]map[int]KeyInfo
+}
+
+type keyStoreHolder struct {
+	store KeyStore
+}
+
+var defaultKeyStore atomic.Pointer[keyStoreHolder]
+
+// defaultSecretStore holds the named AES secrets. It is kept apart from the
+// RSA keys so a secret and an RSA key may share a name.
+var defaultSecretStore atomic.Pointer[keyStoreHolder]
+
+func init() {
+	defaultKeyStore.Store(&keyStoreHolder{store: NewMemoryKeyStore()})
+	defaultSecretStore.Store(&keyStoreHolder{store: NewMemoryKeyStore()})
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		keys: make(map[string]map[int]KeyInfo),
+	}
+}
+
+func (s *MemoryKeyStore) Get(keyName string, version int) *KeyInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keyInfo, ok := s.keys[keyName][version]
+	if !ok {
+		return nil
+	}
+	return &keyInfo
+}
+
+func (s *MemoryKeyStore) Latest(keyName string) *KeyInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var latestKeyInfo *KeyInfo
+	for _, keyInfo := range s.keys[keyName] {
+		keyInfo := keyInfo
+		if !keyInfo.CanEncrypt() {
+			continue
+		}
+		if latestKeyInfo == nil || keyInfo.GetVersion() > latestKeyInfo.GetVersion() {
+			latestKeyInfo = &keyInfo
+		}
+	}
+	return latestKeyInfo
+}
+
+func (s *MemoryKeyStore) Put(keyInfo KeyInfo) error {
+	if keyInfo.GetName() == "" || keyInfo.GetVersion() < 1 {
+		return errors.ErrInvalidKeyInfo
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[keyInfo.GetName()]; !ok {
+		s.keys[keyInfo.GetName()] = make(map[int]KeyInfo)
+	}
+	s.keys[keyInfo.GetName()][keyInfo.GetVersion()] = keyInfo
+	return nil
+}
+
+func (s *MemoryKeyStore) SetState(keyName string, version int, state KeyState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keyInfo, ok := s.keys[keyName][version]
+	if !ok {
+		return &errors.KeyError{KeyName: keyName, KeyVersion: version, Err: errors.ErrKeyNotFound}
+	}
+	keyInfo.SetState(state)
+	s.keys[keyName][version] = keyInfo
+	return nil
+}
+
+func (s *MemoryKeyStore) Delete(keyName string, version int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version == 0 {
+		delete(s.keys, keyName)
+		return
+	}
+	delete(s.keys[keyName], version)
+	if len(s.keys[keyName]) == 0 {
+		delete(s.keys, keyName)
+	}
+}
+
+func (s *MemoryKeyStore) List() []KeyInfo {
+	s.mu.RLock()
+	keys := make([]KeyInfo, 0)
+	for _, versions := range s.keys {
+		for _, keyInfo := range versions {
+			keys = append(keys, keyInfo)
+		}
+	}
+	s.mu.RUnlock()
+	sortKeyInfos(keys)
+	return keys
+}
+
+func (s *MemoryKeyStore) Replace(keys []KeyInfo) error {
+	// build the new key set before taking the lock so readers are only
+	// blocked for the swap itself
+	next := make(map[string]map[int]KeyInfo)
+	for _, keyInfo := range keys {
+		if keyInfo.GetName() == "" || keyInfo.GetVersion() < 1 {
+			return errors.ErrInvalidKeyInfo
+		}
+		if _, ok := next[keyInfo.GetName()]; !ok {
+			next[keyInfo.GetName()] = make(map[int]KeyInfo)
+		}
+		next[keyInfo.GetName()][keyInfo.GetVersion()] = keyInfo
+	}
+	s.mu.Lock()
+	s.keys = next
+	s.mu.Unlock()
+	return nil
+}
+
+// DefaultKeyStore returns the store used by the package level encrypt and
+// decrypt functions.
+func DefaultKeyStore() KeyStore {
+	return defaultKeyStore.Load().store
+}
+
+// SetDefaultKeyStore replaces the store used by the package level encrypt and
+// decrypt functions. A nil store resets it to an empty in-memory store.
+func SetDefaultKeyStore(store KeyStore) {
+	if store == nil {
+		store = NewMemoryKeyStore()
+	}
+	defaultKeyStore.Store(&keyStoreHolder{store: store})
+}
+
+// DefaultSecretStore returns the store of named AES secrets used by the
+// package level aes.EncryptNamed and aes.DecryptNamed functions.
+func DefaultSecretStore() KeyStore {
+	return defaultSecretStore.Load().store
+}
+
+// SetDefaultSecretStore replaces the store of named AES secrets. A nil store
+// resets it to an empty in-memory store.
+func SetDefaultSecretStore(store KeyStore) {
+	if store == nil {
+		store = NewMemoryKeyStore()
+	}
+	defaultSecretStore.Store(&keyStoreHolder{store: store})
+}
+
+// LookupKey returns the given version of keyName from store, or its latest
+// active version when version is 0.
+func LookupKey(store KeyStore, keyName string, version int) *KeyInfo {
+	if store == nil {
+		return nil
+	}
+	if version > 0 {
+		return store.Get(keyName, version)
+	}
+	return store.Latest(keyName)
+}
+
+// FindKey behaves like LookupKey but returns a *errors.KeyError when the key
+// is missing: wrapping errors.ErrVersionNotFound when other versions of
+// keyName are stored and errors.ErrKeyNotFound otherwise.
+func FindKey(store KeyStore, keyName string, version int) (*KeyInfo, error) {
+	if keyInfo := LookupKey(store, keyName, version); keyInfo != nil {
+		return keyInfo, nil
+	}
+	err := errors.ErrKeyNotFound
+	if version > 0 && hasKey(store, keyName) {
+		err = errors.ErrVersionNotFound
+	}
+	return nil, &errors.KeyError{KeyName: keyName, KeyVersion: version, Err: err}
+}
+
+// FindDecryptionKey behaves like FindKey and also rejects revoked versions
+// with errors.ErrKeyRevoked.
+func FindDecryptionKey(store KeyStore, keyName string, version int) (*KeyInfo, error) {
+	keyInfo, err := FindKey(store, keyName, version)
+	if err != nil {
+		return nil, err
+	}
+	if !keyInfo.CanDecrypt() {
+		return nil, &errors.KeyError{KeyName: keyName, KeyVersion: version, Err: errors.ErrKeyRevoked}
+	}
+	return keyInfo, nil
+}
+
+func hasKey(store KeyStore, keyName string) bool {
+	if store == nil {
+		return false
+	}
+	for _, keyInfo := range store.List() {
+		if keyInfo.GetName() == keyName {
+			return true
+		}
+	}
+	return false
+}
+
+// SetKeyState changes the state of the given version of keyName in store.
+// The change is atomic for stores implementing StateSetter, like
+// MemoryKeyStore; other stores get a Get followed by a Put, which a
+// concurrent Replace may interleave with.
+func SetKeyState(store KeyStore, keyName string, version int, state KeyState) error {
+	if setter, ok := store.(StateSetter); ok {
+		return setter.SetState(keyName, version, state)
+	}
+	keyInfo := store.Get(keyName, version)
+	if keyInfo == nil {
+		return &errors.KeyError{KeyName: keyName, KeyVersion: version, Err: errors.ErrKeyNotFound}
+	}
+	keyInfo.SetState(state)
+	return store.Put(*keyInfo)
+}
+
+func sortKeyInfos(keys []KeyInfo) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].GetName() != keys[j].GetName() {
+			return keys[i].GetName() < keys[j].GetName()
+		}
+		return keys[i].GetVersion() < keys[j].GetVersion()
+	})
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	stdErrors "errors"
+	"sync"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+)
+
+func TestMemoryKeyStoreSetState(t *testing.T) {
+	store := NewMemoryKeyStore()
+	if err := store.Put(KeyInfo{Name: "STATEKEY", Version: 1, Secret: []byte("secret")}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if err := SetKeyState(store, "STATEKEY", 1, KeyStateDecryptOnly); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	keyInfo := store.Get("STATEKEY", 1)
+	if keyInfo.GetState() != KeyStateDecryptOnly || string(keyInfo.GetSecret()) != "secret" {
+		t.Errorf("Get() = %v, %q, want DECRYPTONLY with the key kept", keyInfo.GetState(), keyInfo.GetSecret())
+	}
+
+	if err := SetKeyState(store, "STATEKEY", 2, KeyStateRevoked); !stdErrors.Is(err, errors.ErrKeyNotFound) {
+		t.Errorf("SetKeyState() error = %v, want %v", err, errors.ErrKeyNotFound)
+	}
+	if store.Get("STATEKEY", 2) != nil {
+		t.Errorf("Get() = non nil, want SetKeyState not to add keys")
+	}
+}
+
+// TestSetKeyStateConcurrentReplace checks that a state change racing with a
+// Replace never brings back a key version the Replace removed.
+func TestSetKeyStateConcurrentReplace(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		store := NewMemoryKeyStore()
+		if err := store.Put(KeyInfo{Name: "RACEKEY", Version: 1}); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = SetKeyState(store, "RACEKEY", 1, KeyStateRevoked)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = store.Replace([]KeyInfo{{Name: "RACEKEY", Version: 2}})
+		}()
+		wg.Wait()
+
+		if store.Get("RACEKEY", 1) != nil {
+			t.Fatalf("Get() = non nil, want version 1 to stay removed by Replace")
+		}
+	}
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/nected/go-lib/crypto/base64"
 )
@@ -11,8 +13,59 @@ type KeyType string
 const (
 	KeyTypeRSA KeyType = "RSA"
 	KeyTypeAES KeyType = "AES"
+	// KeyTypeAESSIV payloads are encrypted deterministically with AES-SIV:
+	// equal plaintexts under the same key version have equal ciphertexts.
+	KeyTypeAESSIV KeyType = "AES_SIV"
+	// KeyTypeRSAEnvelope payloads are encrypted with a random AES-256-GCM data
+	// key that is itself encrypted with the RSA key, or with ECIES for EC keys.
+	KeyTypeRSAEnvelope KeyType = "RSA_ENVELOPE"
+	// KeyTypeECIES payloads are encrypted with ECIES to an ECDSA or X25519 key.
+	KeyTypeECIES KeyType = "ECIES"
+	// KeyTypeRSAPSSSHA256 and KeyTypeRSAPSSSHA512 mark RSA-PSS signatures
+	// rather than encrypted data.
+	KeyTypeRSAPSSSHA256 KeyType = "RSA_PSS_SHA256"
+	KeyTypeRSAPSSSHA512 KeyType = "RSA_PSS_SHA512"
+	// KeyTypeECDSASHA256 and KeyTypeEd25519 mark ECDSA and Ed25519 signatures.
+	KeyTypeECDSASHA256 KeyType = "ECDSA_SHA256"
+	KeyTypeEd25519     KeyType = "ED25519"
+	// KeyTypeBlindIndex marks the HMAC tokens of crypto/blindindex.
+	KeyTypeBlindIndex KeyType = "BLIND_INDEX"
 )
 
+// wireTagSeparator separates the wire tag of a key type from its encrypted
+// data. It is never part of standard base64, so untagged legacy data can
+// not be mistaken for tagged data.
+const wireTagSeparator = ":"
+
+// wireTags maps key types to the tag prefixed to their encrypted data in the
+// serialized payload. Key types without a tag use the legacy RSA format.
+var wireTags = map[KeyType]string{
+	KeyTypeRSAEnvelope:  "env",
+	KeyTypeAES:          "aes",
+	KeyTypeAESSIV:       "siv",
+	KeyTypeECIES:        "ecies",
+	KeyTypeRSAPSSSHA256: "ps256",
+	KeyTypeRSAPSSSHA512: "ps512",
+	KeyTypeECDSASHA256:  "es256",
+	KeyTypeEd25519:      "ed25519",
+	KeyTypeBlindIndex:   "bidx",
+}
+
+// SplitKeyType splits the encrypted data section of a serialized payload into
+// its key type and encrypted data. Untagged data is reported as KeyTypeRSA.
+func SplitKeyType(encryptedData string) (KeyType, string) {
+	tag, data, found := strings.Cut(encryptedData, wireTagSeparator)
+	if !found {
+		return KeyTypeRSA, encryptedData
+	}
+	for keyType, keyTypeTag := range wireTags {
+		if keyTypeTag == tag {
+			return keyType, data
+		}
+	}
+	return KeyTypeRSA, encryptedData
+}
+
 type Payload struct {
 	// Payload is the data to be encrypted
 	KeyName          string  `json:"keyName"`
@@ -21,15 +74,95 @@ type Payload struct {
 	Data             string  `json:"data"`
 	EncryptedData    string  `json:"encryptedData"`
 	AlreadyEncrypted bool    `json:"alreadyEncrypted"`
+	// AssociatedData is the context the ciphertext is bound to. It is not
+	// part of the serialized payload and must be supplied again to decrypt.
+	AssociatedData string `json:"associatedData,omitempty"`
 }
 
+// String serializes the payload as base64("$keyName$keyVersion$" + data),
+// where data is the encrypted data prefixed with the wire tag of the key type.
+// Payloads without a key name, such as those of aes.Encrypt, are returned as
+// the bare encrypted data.
 func (p *Payload) String() string {
 	if p.KeyName == "" {
 		return p.EncryptedData
 	}
-	if p.KeyType == KeyTypeAES {
-		return p.EncryptedData
+	encryptedData := p.EncryptedData
+	if tag, ok := wireTags[p.KeyType]; ok {
+		encryptedData = tag + wireTagSeparator + encryptedData
 	}
-	data := fmt.Sprintf("$%s$%v$%s", p.KeyName, p.KeyVersion, p.EncryptedData)
+	data := fmt.Sprintf("$%s$%v$%s", p.KeyName, p.KeyVersion, encryptedData)
 	return base64.B64Encode([]byte(data))
 }
+
+// ParsePayload parses a value returned by Payload.String. Only the key name,
+// key version, key type and encrypted data are set on the returned payload.
+// It returns nil when data is not a serialized payload, e.g. plain text or the
+// output of aes.Encrypt.
+func ParsePayload(data string) *Payload {
+	decodedData, err := base64.B64Decode(data)
+	if err != nil {
+		return nil
+	}
+	keyName, keyVersion, encryptedData := ParseHeader(decodedData)
+	if keyName == "" || keyVersion == 0 || encryptedData == "" {
+		return nil
+	}
+	keyType, encryptedData := SplitKeyType(encryptedData)
+	return &Payload{
+		KeyName:       keyName,
+		KeyVersion:    keyVersion,
+		KeyType:       keyType,
+		EncryptedData: encryptedData,
+	}
+}
+
+// ParseHeader parses a string containing key name, key version, and encrypted data
+// separated by '$' characters. It returns the key name, key version, and encrypted data.
+//
+// The input string is expected to be in the format: "$keyName$keyVersion$encryptedData".
+//
+// Parameters:
+// - data: A string containing the key name, key version, and encrypted data.
+//
+// Returns:
+// - keyName: The extracted key name.
+// - keyVersion: The extracted key version.
+// - encryptedData: The extracted encrypted data.
+func ParseHeader(data string) (string, int, string) {
+	keyName := ""
+	keyVersion := 0
+	encryptedData := ""
+
+	var err error
+
+	if len(data) == 0 {
+		return keyName, keyVersion, encryptedData
+	}
+
+	// if data is not encrypted return as is
+	if data[0] != '$' {
+		encryptedData = data
+		return keyName, keyVersion, encryptedData
+	}
+
+	for i := 1; i < len(data); i++ {
+		if data[i] == '$' {
+			if keyName == "" {
+				keyName = data[1:i]
+				continue
+			}
+			if keyVersion == 0 {
+				keyVersionStr := data[len(keyName)+2 : i]
+				if keyVersion, err = strconv.Atoi(keyVersionStr); err != nil {
+					keyVersion = 0
+					break
+				}
+				encryptedData = data[i+1:]
+				break
+			}
+		}
+	}
+
+	return keyName, keyVersion, encryptedData
+}
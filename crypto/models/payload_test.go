@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestParseHeader(t *testing.T) {
+	type args struct {
+		data string
+	}
+	tests := []struct {
+		name  string
+		args  args
+		want  string
+		want1 int
+		want2 string
+	}{
+		{
+			name: "Test 1",
+			args: args{
+				data: "$keyName$1$encryptedData",
+			},
+			want:  "keyName",
+			want1: 1,
+			want2: "encryptedData",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1, got2 := ParseHeader(tt.args.data)
+			if got != tt.want {
+				t.Errorf("ParseHeader() got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("ParseHeader() got1 = %v, want %v", got1, tt.want1)
+			}
+			if got2 != tt.want2 {
+				t.Errorf("ParseHeader() got2 = %v, want %v", got2, tt.want2)
+			}
+		})
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload *Payload
+		data    string
+		want    *Payload
+	}{
+		{
+			name:    "RSA",
+			payload: &Payload{KeyName: "KEY", KeyVersion: 2, KeyType: KeyTypeRSA, EncryptedData: "ZGF0YQ=="},
+			want:    &Payload{KeyName: "KEY", KeyVersion: 2, KeyType: KeyTypeRSA, EncryptedData: "ZGF0YQ=="},
+		},
+		{
+			name:    "RSA envelope",
+			payload: &Payload{KeyName: "KEY", KeyVersion: 1, KeyType: KeyTypeRSAEnvelope, EncryptedData: "ZGF0YQ=="},
+			want:    &Payload{KeyName: "KEY", KeyVersion: 1, KeyType: KeyTypeRSAEnvelope, EncryptedData: "ZGF0YQ=="},
+		},
+		{
+			name:    "named AES",
+			payload: &Payload{KeyName: "SECRET", KeyVersion: 3, KeyType: KeyTypeAES, EncryptedData: "ZGF0YQ=="},
+			want:    &Payload{KeyName: "SECRET", KeyVersion: 3, KeyType: KeyTypeAES, EncryptedData: "ZGF0YQ=="},
+		},
+		{
+			name:    "unnamed AES",
+			payload: &Payload{KeyType: KeyTypeAES, EncryptedData: "ZGF0YQ"},
+			want:    nil,
+		},
+		{
+			name: "plain text",
+			data: "hello world",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data
+			if tt.payload != nil {
+				data = tt.payload.String()
+			}
+			got := ParsePayload(data)
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("ParsePayload() = %v, want %v", got, tt.want)
+			}
+			if got != nil && *got != *tt.want {
+				t.Errorf("ParsePayload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/nected/go-lib/algo"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+	"github.com/nected/go-lib/crypto/rsa"
+)
+
+// EncryptPaths encrypts, in place, the string values of doc found at paths,
+// replacing each of them with its Payload.String() ciphertext. Paths are
+// resolved by algo.LookupValInSource, with the syntax of
+// algo.GetValFromSource, e.g. "user.emails[0]".
+//
+// Parameters:
+//   - doc: The document to modify.
+//   - keyName: The name of the encryption key to use.
+//   - paths: The paths of the values to encrypt.
+//
+// Returns:
+//   - []string: The paths that are not present in doc: a key that is not
+//     found, or an index past the end of a list.
+//   - error: errors.ErrInvalidPath if a path can not be parsed or does not
+//     fit doc, e.g. an index into an object, or an error if a value is not
+//     a string or can not be encrypted.
+func EncryptPaths(doc map[string]any, keyName string, paths []string) ([]string, error) {
+	return EncryptPathsWithStore(models.DefaultKeyStore(), doc, keyName, paths)
+}
+
+// EncryptPathsWithStore behaves like EncryptPaths but looks the key up in
+// store instead of the default store.
+func EncryptPathsWithStore(store models.KeyStore, doc map[string]any, keyName string, paths []string) ([]string, error) {
+	return transformPaths(doc, paths, func(data string) (string, error) {
+		payload, err := rsa.EncryptWithStore(store, keyName, []byte(data))
+		if err != nil {
+			return "", err
+		}
+		return payload.String(), nil
+	})
+}
+
+// DecryptPaths reverses EncryptPaths. Values that are not encrypted are left
+// untouched.
+func DecryptPaths(doc map[string]any, paths []string) ([]string, error) {
+	return DecryptPathsWithStore(models.DefaultKeyStore(), doc, paths)
+}
+
+// DecryptPathsWithStore behaves like DecryptPaths but looks keys up in store
+// instead of the default store.
+func DecryptPathsWithStore(store models.KeyStore, doc map[string]any, paths []string) ([]string, error) {
+	return transformPaths(doc, paths, func(data string) (string, error) {
+		payload, err := rsa.DecryptWithStore(store, data)
+		if err != nil {
+			return "", err
+		}
+		return payload.Data, nil
+	})
+}
+
+func transformPaths(doc map[string]any, paths []string, transform func(string) (string, error)) ([]string, error) {
+	missing := make([]string, 0)
+	for _, path := range paths {
+		val, found, err := algo.LookupValInSource(doc, path)
+		if err != nil {
+			return missing, fmt.Errorf("%w: %q: %v", errors.ErrInvalidPath, path, err)
+		}
+		if !found {
+			missing = append(missing, path)
+			continue
+		}
+		if val == nil {
+			continue
+		}
+		data, ok := val.(string)
+		if !ok {
+			return missing, fmt.Errorf("%s: value of type %T is not a string", path, val)
+		}
+		if data == "" {
+			continue
+		}
+		transformed, err := transform(data)
+		if err != nil {
+			return missing, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := algo.SetValInSource(doc, path, transformed); err != nil {
+			// e.g. a character of a string, which can be read but not set
+			return missing, fmt.Errorf("%w: %q: %v", errors.ErrInvalidPath, path, err)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	stdErrors "errors"
+	"reflect"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+)
+
+func TestEncryptPathsWithStore(t *testing.T) {
+	store := newFieldTestStore(t)
+	doc := map[string]any{
+		"name": "name",
+		"user": map[string]any{
+			"email":  "a@b.c",
+			"phones": []any{"123", "456"},
+		},
+		"cards": []any{map[string]any{"number": "4111"}},
+		"age":   42,
+		"empty": "",
+	}
+	paths := []string{"user.email", "user.phones[1]", "cards[0].number", "user.missing", "cards[3].number", "empty"}
+
+	missing, err := EncryptPathsWithStore(store, doc, "FIELDKEY", paths)
+	if err != nil {
+		t.Fatalf("EncryptPathsWithStore() error = %v", err)
+	}
+	if want := []string{"user.missing", "cards[3].number"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("EncryptPathsWithStore() missing = %v, want %v", missing, want)
+	}
+
+	user := doc["user"].(map[string]any)
+	for _, val := range []any{user["email"], user["phones"].([]any)[1], doc["cards"].([]any)[0].(map[string]any)["number"]} {
+		payload, err := DecryptRSAWithStore(store, val.(string))
+		if err != nil || payload.KeyName != "FIELDKEY" {
+			t.Errorf("value %q is not encrypted with FIELDKEY", val)
+		}
+	}
+	if doc["name"] != "name" || user["phones"].([]any)[0] != "123" || doc["empty"] != "" {
+		t.Errorf("EncryptPathsWithStore() changed values outside paths: %v", doc)
+	}
+
+	missing, err = DecryptPathsWithStore(store, doc, paths)
+	if err != nil {
+		t.Fatalf("DecryptPathsWithStore() error = %v", err)
+	}
+	if len(missing) != 2 {
+		t.Errorf("DecryptPathsWithStore() missing = %v, want 2 paths", missing)
+	}
+	if user["email"] != "a@b.c" || user["phones"].([]any)[1] != "456" || doc["cards"].([]any)[0].(map[string]any)["number"] != "4111" {
+		t.Errorf("DecryptPathsWithStore() = %v, want original values", doc)
+	}
+
+	if _, err := EncryptPathsWithStore(store, doc, "FIELDKEY", []string{"age"}); err == nil {
+		t.Errorf("EncryptPathsWithStore() error = nil, want error for non string value")
+	}
+}
+
+func TestEncryptPathsInvalidPath(t *testing.T) {
+	store := newFieldTestStore(t)
+	tests := []struct {
+		name    string
+		path    string
+		missing bool
+		wantErr error
+	}{
+		{"Absent key", "user.missing", true, nil},
+		{"Index past the end", "user.phones[5]", true, nil},
+		{"Key of null", "nothing.deeper", true, nil},
+		{"Index into object", "user[0]", false, errors.ErrInvalidPath},
+		{"Index into string", "user.email[0]", false, errors.ErrInvalidPath},
+		{"Key of list", "user.phones.first", false, errors.ErrInvalidPath},
+		// keys are read like algo.GetValFromSource reads them
+		{"Empty segment", "user..email", true, nil},
+		{"Non numeric index", "user.phones[x]", true, nil},
+		{"Key with a space", "user.user name", true, nil},
+		{"Key with symbols", "e$[0]", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := map[string]any{
+				"user":    map[string]any{"email": "a@b.c", "phones": []any{"123"}, "user name": "jane"},
+				"nothing": nil,
+				"e$[0]":   "value",
+			}
+			missing, err := EncryptPathsWithStore(store, doc, "FIELDKEY", []string{tt.path})
+			if !stdErrors.Is(err, tt.wantErr) {
+				t.Errorf("EncryptPathsWithStore() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := len(missing) == 1; got != tt.missing {
+				t.Errorf("EncryptPathsWithStore() missing = %v, want missing %v", missing, tt.missing)
+			}
+		})
+	}
+}
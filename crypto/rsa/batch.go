@@ -0,0 +1,150 @@
+package rsa
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// BatchResult is the outcome of one item of EncryptBatch or DecryptBatch.
+type BatchResult struct {
+	// Payload is the encrypted or decrypted item, nil if Err is set.
+	Payload *models.Payload
+	// Err is the error of the item, or the error of the context if the batch
+	// was cancelled before the item was processed.
+	Err error
+}
+
+type batchOptions struct {
+	store          models.KeyStore
+	workers        int
+	additionalData []byte
+}
+
+func newBatchOptions(opts []BatchOption) *batchOptions {
+	o := &batchOptions{
+		store:   models.DefaultKeyStore(),
+		workers: runtime.GOMAXPROCS(0),
+	}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	return o
+}
+
+type BatchOption interface {
+	apply(*batchOptions)
+}
+
+// batchOptionFunc wraps a func so it satisfies the BatchOption interface.
+type batchOptionFunc func(*batchOptions)
+
+func (f batchOptionFunc) apply(o *batchOptions) {
+	f(o)
+}
+
+// WithStore looks keys up in store instead of the default store.
+func WithStore(store models.KeyStore) BatchOption {
+	return batchOptionFunc(func(o *batchOptions) {
+		o.store = store
+	})
+}
+
+// WithWorkers bounds the number of items processed at the same time, the
+// number of CPUs by default. RSA is CPU bound, so more workers than CPUs
+// rarely help.
+func WithWorkers(workers int) BatchOption {
+	return batchOptionFunc(func(o *batchOptions) {
+		o.workers = workers
+	})
+}
+
+// WithAAD binds every item to additionalData, like EncryptWithAAD and
+// DecryptWithAAD.
+func WithAAD(additionalData []byte) BatchOption {
+	return batchOptionFunc(func(o *batchOptions) {
+		o.additionalData = additionalData
+	})
+}
+
+// EncryptBatch encrypts every item with the latest active version of the named
+// key, like Encrypt, spreading the items over a bounded pool of workers.
+//
+// Parameters:
+//   - ctx: Cancelling ctx stops the batch; the items not yet processed fail
+//     with the context's error.
+//   - keyName: The name of the key to use.
+//   - items: The data to be encrypted.
+//   - opts: WithStore, WithWorkers and WithAAD.
+//
+// Returns:
+//   - []BatchResult: One result per item, in the order of items.
+//   - error: The context's error if the batch was cancelled, nil otherwise.
+//     The errors of single items are only reported in their result.
+func EncryptBatch(ctx context.Context, keyName string, items [][]byte, opts ...BatchOption) ([]BatchResult, error) {
+	o := newBatchOptions(opts)
+	return runBatch(ctx, len(items), o.workers, func(i int) (*models.Payload, error) {
+		return encrypt(o.store, keyName, items[i], o.additionalData, encryptWithKey)
+	})
+}
+
+// DecryptBatch decrypts every item like Decrypt, spreading the items over a
+// bounded pool of workers. See EncryptBatch for the parameters and results.
+func DecryptBatch(ctx context.Context, items []string, opts ...BatchOption) ([]BatchResult, error) {
+	o := newBatchOptions(opts)
+	return runBatch(ctx, len(items), o.workers, func(i int) (*models.Payload, error) {
+		return decrypt(o.store, items[i], o.additionalData, false)
+	})
+}
+
+// runBatch calls fn for the indexes [0, n) on at most workers goroutines.
+func runBatch(ctx context.Context, n, workers int, fn func(i int) (*models.Payload, error)) ([]BatchResult, error) {
+	results := make([]BatchResult, n)
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					continue
+				}
+				results[i].Payload, results[i].Err = fn(i)
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < n; next++ {
+		select {
+		case indexes <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for ; next < n; next++ {
+		results[next].Err = ctx.Err()
+	}
+	// a context cancelled after the last item does not fail the batch
+	for _, result := range results {
+		if result.Err != nil && result.Err == ctx.Err() {
+			return results, result.Err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,193 @@
+package rsa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	cryptoErrors "github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func newBatchItems(n int) [][]byte {
+	items := make([][]byte, n)
+	for i := range items {
+		items[i] = []byte(fmt.Sprintf("field value %d", i))
+	}
+	return items
+}
+
+func TestEncryptBatch(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "BATCHKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	items := newBatchItems(50)
+
+	for _, workers := range []int{0, 1, 4, 100} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			encrypted, err := EncryptBatch(context.Background(), "BATCHKEY", items, WithStore(store), WithWorkers(workers))
+			if err != nil {
+				t.Fatalf("EncryptBatch() error = %v", err)
+			}
+			if len(encrypted) != len(items) {
+				t.Fatalf("EncryptBatch() returned %d results, want %d", len(encrypted), len(items))
+			}
+
+			ciphertexts := make([]string, len(encrypted))
+			for i, result := range encrypted {
+				if result.Err != nil {
+					t.Fatalf("EncryptBatch() item %d error = %v", i, result.Err)
+				}
+				ciphertexts[i] = result.Payload.String()
+			}
+
+			decrypted, err := DecryptBatch(context.Background(), ciphertexts, WithStore(store), WithWorkers(workers))
+			if err != nil {
+				t.Fatalf("DecryptBatch() error = %v", err)
+			}
+			for i, result := range decrypted {
+				if result.Err != nil {
+					t.Fatalf("DecryptBatch() item %d error = %v", i, result.Err)
+				}
+				if result.Payload.Data != string(items[i]) {
+					t.Errorf("DecryptBatch() item %d = %v, want %v", i, result.Payload.Data, string(items[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestDecryptBatchItemErrors(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "BATCHKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	aad := WithAAD([]byte("tenant-1"))
+	encrypted, err := EncryptBatch(context.Background(), "BATCHKEY", newBatchItems(3), WithStore(store), aad)
+	if err != nil {
+		t.Fatalf("EncryptBatch() error = %v", err)
+	}
+	wrongAAD, err := EncryptBatch(context.Background(), "BATCHKEY", [][]byte{[]byte("other tenant")}, WithStore(store), WithAAD([]byte("tenant-2")))
+	if err != nil {
+		t.Fatalf("EncryptBatch() error = %v", err)
+	}
+
+	items := []string{encrypted[0].Payload.String(), "", encrypted[2].Payload.String(), wrongAAD[0].Payload.String()}
+	results, err := DecryptBatch(context.Background(), items, WithStore(store), aad)
+	if err != nil {
+		t.Fatalf("DecryptBatch() error = %v", err)
+	}
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Errorf("DecryptBatch() errors = %v, %v, want nil", results[0].Err, results[2].Err)
+	}
+	if !errors.Is(results[1].Err, cryptoErrors.ErrEmptyData) {
+		t.Errorf("DecryptBatch() item 1 error = %v, want %v", results[1].Err, cryptoErrors.ErrEmptyData)
+	}
+	if !errors.Is(results[3].Err, cryptoErrors.ErrAuthenticationFailed) {
+		t.Errorf("DecryptBatch() item 3 error = %v, want %v", results[3].Err, cryptoErrors.ErrAuthenticationFailed)
+	}
+}
+
+func TestEncryptBatchCancelled(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "BATCHKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := EncryptBatch(ctx, "BATCHKEY", newBatchItems(10), WithStore(store))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("EncryptBatch() error = %v, want %v", err, context.Canceled)
+	}
+	if len(results) != 10 {
+		t.Fatalf("EncryptBatch() returned %d results, want %d", len(results), 10)
+	}
+	for i, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("EncryptBatch() item %d error = %v, want %v", i, result.Err, context.Canceled)
+		}
+	}
+}
+
+func TestEncryptBatchEmpty(t *testing.T) {
+	results, err := EncryptBatch(context.Background(), "BATCHKEY", nil)
+	if err != nil || len(results) != 0 {
+		t.Errorf("EncryptBatch() = %v, %v, want no results", results, err)
+	}
+}
+
+func newBenchmarkStore(b *testing.B) *models.MemoryKeyStore {
+	b.Helper()
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(b, "BENCHKEY", 1)); err != nil {
+		b.Fatalf("Put() error = %v", err)
+	}
+	return store
+}
+
+const benchmarkBatchSize = 100
+
+func BenchmarkEncryptSequential(b *testing.B) {
+	store := newBenchmarkStore(b)
+	items := newBatchItems(benchmarkBatchSize)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, item := range items {
+			if _, err := EncryptWithStore(store, "BENCHKEY", item); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkEncryptBatch(b *testing.B) {
+	store := newBenchmarkStore(b)
+	items := newBatchItems(benchmarkBatchSize)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := EncryptBatch(context.Background(), "BENCHKEY", items, WithStore(store)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkCiphertexts(b *testing.B, store models.KeyStore) []string {
+	b.Helper()
+	results, err := EncryptBatch(context.Background(), "BENCHKEY", newBatchItems(benchmarkBatchSize), WithStore(store))
+	if err != nil {
+		b.Fatal(err)
+	}
+	ciphertexts := make([]string, len(results))
+	for i, result := range results {
+		ciphertexts[i] = result.Payload.String()
+	}
+	return ciphertexts
+}
+
+func BenchmarkDecryptSequential(b *testing.B) {
+	store := newBenchmarkStore(b)
+	ciphertexts := benchmarkCiphertexts(b, store)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, ciphertext := range ciphertexts {
+			if _, err := DecryptWithStore(store, ciphertext); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecryptBatch(b *testing.B) {
+	store := newBenchmarkStore(b)
+	ciphertexts := benchmarkCiphertexts(b, store)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := DecryptBatch(context.Background(), ciphertexts, WithStore(store)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package rsa
+
+import (
+	"crypto/rand"
+
+	"github.com/nected/go-lib/crypto/aes"
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// dataKeySize is the size of the AES-256 data key used by envelope encryption.
+const dataKeySize = 32
+
+// EncryptEnvelope encrypts the given data with a random AES-256-GCM data key
+// and encrypts only that data key with the specified RSA key. Unlike Encrypt,
+// the cost and size overhead stay constant regardless of the data size, which
+// makes it the better choice for large payloads.
+//
+// Already encrypted data and unknown keys are handled like Encrypt. The
+// result can be decrypted with Decrypt, which detects the envelope format.
+//
+// Parameters:
+//   - keyName: The name of the encryption key to use.
+//   - data: The data to be encrypted.
+//
+// Returns:
+//   - *models.Payload: A payload of type models.KeyTypeRSAEnvelope.
+//   - error: An error if the encryption process fails.
+func EncryptEnvelope(keyName string, data []byte) (*models.Payload, error) {
+	return EncryptEnvelopeWithStore(models.DefaultKeyStore(), keyName, data)
+}
+
+// EncryptEnvelopeWithStore behaves like EncryptEnvelope but looks the key up
+// in store instead of the default store.
+func EncryptEnvelopeWithStore(store models.KeyStore, keyName string, data []byte) (*models.Payload, error) {
+	return encrypt(store, keyName, data, nil, encryptEnvelopeWithKey)
+}
+
+// EncryptEnvelopeWithAAD behaves like EncryptEnvelope but binds the
+// ciphertext to additionalData. Decrypt it with DecryptWithAAD.
+func EncryptEnvelopeWithAAD(keyName string, data, additionalData []byte) (*models.Payload, error) {
+	return encrypt(models.DefaultKeyStore(), keyName, data, additionalData, encryptEnvelopeWithKey)
+}
+
+// encryptEnvelopeWithKey seals data under a fresh data key and serializes the
+// result as wrappedDataKey||nonce||ciphertext. The wrapped data key is always
+// keyInfo.CiphertextSize(dataKeySize) bytes long, one block for RSA keys.
+func encryptEnvelopeWithKey(keyInfo *models.KeyInfo, data, additionalData []byte) (*models.Payload, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	wrappedDataKey, err := keyInfo.Encrypt(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// bind the ciphertext to the key that wrapped its data key
+	sealedData, err := aes.Seal(dataKey, data, keyInfo.Label(additionalData))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Payload{
+		KeyName:        keyInfo.GetName(),
+		KeyVersion:     keyInfo.GetVersion(),
+		KeyType:        models.KeyTypeRSAEnvelope,
+		Data:           string(data),
+		EncryptedData:  base64.B64Encode(append(wrappedDataKey, sealedData...)),
+		AssociatedData: string(additionalData),
+	}, nil
+}
+
+// decryptEnvelopeWithKey reverses encryptEnvelopeWithKey.
+func decryptEnvelopeWithKey(keyInfo *models.KeyInfo, encryptedData, additionalData []byte) ([]byte, error) {
+	wrappedSize, err := keyInfo.CiphertextSize(dataKeySize)
+	if err != nil {
+		return nil, err
+	}
+	if len(encryptedData) < wrappedSize {
+		return nil, errors.ErrTruncatedData
+	}
+
+	dataKey, unwrapErr := keyInfo.Decrypt(encryptedData[:wrappedSize])
+	if unwrapErr != nil {
+		// still open the data with a throwaway key, so a bad data key takes
+		// as long as bad data
+		dataKey = make([]byte, dataKeySize)
+	}
+
+	decryptedData, err := aes.Open(dataKey, encryptedData[wrappedSize:], keyInfo.Label(additionalData))
+	if unwrapErr != nil {
+		return nil, unwrapErr
+	}
+	return decryptedData, err
+}
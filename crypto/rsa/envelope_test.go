@@ -0,0 +1,117 @@
+package rsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func TestEncryptEnvelopeWithStore(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "ENVKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	largeData := make([]byte, 1<<20)
+	if _, err := rand.Read(largeData); err != nil {
+		t.Fatalf("rand.Read() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "small data", data: []byte("test data")},
+		{name: "empty data", data: []byte("")},
+		{name: "large data", data: largeData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncryptEnvelopeWithStore(store, "ENVKEY", tt.data)
+			if err != nil {
+				t.Fatalf("EncryptEnvelopeWithStore() error = %v", err)
+			}
+			if got.KeyType != models.KeyTypeRSAEnvelope || got.KeyName != "ENVKEY" || got.KeyVersion != 1 {
+				t.Fatalf("EncryptEnvelopeWithStore() = %v, want envelope payload for ENVKEY version 1", got)
+			}
+
+			payload, err := DecryptWithStore(store, got.String())
+			if err != nil {
+				t.Fatalf("DecryptWithStore() error = %v", err)
+			}
+			if payload.KeyType != models.KeyTypeRSAEnvelope {
+				t.Errorf("DecryptWithStore() KeyType = %v, want %v", payload.KeyType, models.KeyTypeRSAEnvelope)
+			}
+			if !bytes.Equal([]byte(payload.Data), tt.data) {
+				t.Errorf("DecryptWithStore() data mismatch")
+			}
+		})
+	}
+
+	// the envelope overhead is constant: one RSA block, the nonce and the tag
+	got, err := EncryptEnvelopeWithStore(store, "ENVKEY", largeData)
+	if err != nil {
+		t.Fatalf("EncryptEnvelopeWithStore() error = %v", err)
+	}
+	sealed, err := base64.B64Decode(got.EncryptedData)
+	if err != nil {
+		t.Fatalf("B64Decode() error = %v", err)
+	}
+	if overhead := len(sealed) - len(largeData); overhead != 256+12+16 {
+		t.Errorf("envelope overhead = %v bytes, want %v", overhead, 256+12+16)
+	}
+}
+
+func TestEncryptEnvelopeTampered(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "ENVKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, err := EncryptEnvelopeWithStore(store, "ENVKEY", []byte("test data"))
+	if err != nil {
+		t.Fatalf("EncryptEnvelopeWithStore() error = %v", err)
+	}
+
+	sealed, _ := base64.B64Decode(got.EncryptedData)
+	tampered := []byte(sealed)
+	tampered[len(tampered)-1] ^= 0xff
+	got.EncryptedData = base64.B64Encode(tampered)
+	if _, err := DecryptWithStore(store, got.String()); err == nil {
+		t.Errorf("DecryptWithStore() error = nil, want authentication error")
+	}
+
+	got.EncryptedData = base64.B64Encode([]byte("short"))
+	if _, err := DecryptWithStore(store, got.String()); err == nil {
+		t.Errorf("DecryptWithStore() error = nil, want error for truncated envelope")
+	}
+}
+
+func TestRotateEnvelopeKeepsFormat(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "ENVKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, err := EncryptEnvelopeWithStore(store, "ENVKEY", []byte("test data"))
+	if err != nil {
+		t.Fatalf("EncryptEnvelopeWithStore() error = %v", err)
+	}
+	if err := store.Put(testutil.NewKeyInfo(t, "ENVKEY", 2)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	rotated, ok, err := RotateWithStore(store, got.String())
+	if err != nil || !ok {
+		t.Fatalf("RotateWithStore() = %v, %v, want rotated", ok, err)
+	}
+	payload, err := DecryptWithStore(store, rotated)
+	if err != nil {
+		t.Fatalf("DecryptWithStore() error = %v", err)
+	}
+	if payload.KeyType != models.KeyTypeRSAEnvelope || payload.KeyVersion != 2 || payload.Data != "test data" {
+		t.Errorf("DecryptWithStore() = %v, want envelope payload version 2", payload)
+	}
+}
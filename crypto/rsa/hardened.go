@@ -0,0 +1,140 @@
+package rsa
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// decoyKeySize is the size of the RSA key that stands in for unknown keys.
+const decoyKeySize = 2048
+
+var decoy struct {
+	once    sync.Once
+	keyInfo *models.KeyInfo
+	err     error
+}
+
+// DecryptHardened behaves like DecryptStrict but is meant for callers that
+// pass errors on to untrusted clients, e.g. in an HTTP response.
+//
+// Every failure, whether data is malformed, its key is unknown, revoked or
+// public-only, or the ciphertext was tampered with, returns the same
+// errors.ErrDecryptionFailed. When the key can not be used a decoy RSA key
+// decrypts the data instead, so the work done depends only on the length of
+// data and not on the reason of the failure. The decoy is a 2048-bit key:
+// unknown keys can still be told apart by timing from loaded keys of another
+// size or algorithm. Data that is not valid base64 takes the same path as data
+// of an unknown key.
+func DecryptHardened(data string) (*models.Payload, error) {
+	return DecryptHardenedWithStore(models.DefaultKeyStore(), data)
+}
+
+// DecryptHardenedWithStore behaves like DecryptHardened but looks the key up
+// in store instead of the default store.
+func DecryptHardenedWithStore(store models.KeyStore, data string) (*models.Payload, error) {
+	decoyKeyInfo, err := decoyKey()
+	if err != nil {
+		return nil, errors.ErrDecryptionFailed
+	}
+
+	parsed := models.ParsePayload(data)
+	valid := parsed != nil && isRSAKeyType(parsed.KeyType)
+	if !valid {
+		parsed = &models.Payload{KeyType: models.KeyTypeRSA, EncryptedData: data}
+	}
+
+	var keyInfo *models.KeyInfo
+	if valid {
+		keyInfo = models.LookupKey(store, parsed.KeyName, parsed.KeyVersion)
+	}
+	if keyInfo == nil || !keyInfo.CanDecrypt() || !keyInfo.HasPrivateKey() {
+		valid = false
+		keyInfo = decoyKeyInfo
+	}
+
+	encryptedData, err := base64.B64Decode(parsed.EncryptedData)
+	if err != nil {
+		// zeros of the decoded size stand in for the data
+		valid = false
+		keyInfo = decoyKeyInfo
+		encryptedData = string(make([]byte, decodedLen(parsed.EncryptedData)))
+	}
+
+	var decryptedData []byte
+	if parsed.KeyType == models.KeyTypeRSAEnvelope {
+		decryptedData, err = decryptEnvelopeWithKey(keyInfo, []byte(encryptedData), nil)
+	} else {
+		decryptedData, err = decryptAllChunks(keyInfo, []byte(encryptedData))
+	}
+	if err != nil || !valid {
+		return nil, errors.ErrDecryptionFailed
+	}
+
+	return &models.Payload{
+		KeyName:       parsed.KeyName,
+		KeyVersion:    parsed.KeyVersion,
+		KeyType:       parsed.KeyType,
+		Data:          string(decryptedData),
+		EncryptedData: parsed.EncryptedData,
+	}, nil
+}
+
+// decryptAllChunks behaves like KeyInfo.Decrypt, but for RSA keys it
+// decrypts every chunk even after one fails, so the time taken does not
+// reveal which chunk was bad.
+func decryptAllChunks(keyInfo *models.KeyInfo, data []byte) ([]byte, error) {
+	if keyInfo.GetAlgorithm() != models.KeyAlgorithmRSA {
+		return keyInfo.Decrypt(data)
+	}
+	step := keyInfo.GetPubKey().Size()
+	if len(data)%step != 0 {
+		return nil, errors.ErrTruncatedData
+	}
+	label := keyInfo.Label(nil)
+	decryptedData := make([]byte, 0)
+	var firstErr error
+	for i := 0; i < len(data); i += step {
+		decrypted, err := rsa.DecryptOAEP(sha512.New(), rand.Reader, keyInfo.GetPrivKey(), data[i:i+step], label)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		decryptedData = append(decryptedData, decrypted...)
+	}
+	if firstErr != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrAuthenticationFailed, firstErr)
+	}
+	return decryptedData, nil
+}
+
+// decoyKey returns the key used by DecryptHardened when the key of the data
+// can not be used. It is generated on the first call of DecryptHardened,
+// before the data is looked at, so that call takes as long whatever the data.
+func decoyKey() (*models.KeyInfo, error) {
+	decoy.once.Do(func() {
+		privateKey, err := rsa.GenerateKey(rand.Reader, decoyKeySize)
+		if err != nil {
+			decoy.err = err
+			return
+		}
+		decoy.keyInfo = &models.KeyInfo{
+			Name:    "decoy",
+			Version: 1,
+			PrivKey: privateKey,
+			PubKey:  &privateKey.PublicKey,
+		}
+	})
+	return decoy.keyInfo, decoy.err
+}
+
+// decodedLen returns the length of the padded base64 string s once decoded.
+func decodedLen(s string) int {
+	return len(s)/4*3 - (len(s) - len(strings.TrimRight(s, "=")))
+}
@@ -0,0 +1,113 @@
+package rsa
+
+import (
+	"testing"
+
+	"github.com/nected/go-lib/crypto/base64"
+	cryptoErrors "github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func TestDecryptHardenedWithStore(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	for version := 1; version <= 2; version++ {
+		if err := store.Put(testutil.NewKeyInfo(t, "HARDKEY", version)); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	publicKeyInfo := testutil.NewKeyInfo(t, "PUBKEY", 1)
+	publicKeyInfo.PrivKey = nil
+	if err := store.Put(publicKeyInfo); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	encrypted, err := EncryptWithStore(store, "HARDKEY", []byte("test data"))
+	if err != nil {
+		t.Fatalf("EncryptWithStore() error = %v", err)
+	}
+	envelope, err := EncryptEnvelopeWithStore(store, "HARDKEY", []byte("envelope data"))
+	if err != nil {
+		t.Fatalf("EncryptEnvelopeWithStore() error = %v", err)
+	}
+	revoked, err := encryptWithKey(store.Get("HARDKEY", 1), []byte("revoked data"), nil)
+	if err != nil {
+		t.Fatalf("encryptWithKey() error = %v", err)
+	}
+	if err := models.SetKeyState(store, "HARDKEY", 1, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	publicOnly, err := EncryptWithStore(store, "PUBKEY", []byte("test data"))
+	if err != nil {
+		t.Fatalf("EncryptWithStore() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		payload *models.Payload
+		want    string
+	}{
+		{"RSA", encrypted, "test data"},
+		{"Envelope", envelope, "envelope data"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptHardenedWithStore(store, tt.payload.String())
+			if err != nil || got.Data != tt.want {
+				t.Errorf("DecryptHardenedWithStore() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	unknownKey := *encrypted
+	unknownKey.KeyName = "OTHERKEY"
+	unknownVersion := *encrypted
+	unknownVersion.KeyVersion = 3
+	invalidBase64 := *encrypted
+	invalidBase64.EncryptedData = "!" + invalidBase64.EncryptedData[1:]
+	encryptedData, _ := base64.B64Decode(encrypted.EncryptedData)
+	tampered := *encrypted
+	tamperedData := []byte(encryptedData)
+	tamperedData[0] ^= 1
+	tampered.EncryptedData = base64.B64Encode(tamperedData)
+	truncated := *encrypted
+	truncated.EncryptedData = base64.B64Encode([]byte(encryptedData[:len(encryptedData)-1]))
+	tamperedEnvelope := *envelope
+	envelopeData, _ := base64.B64Decode(envelope.EncryptedData)
+	tamperedEnvelopeData := []byte(envelopeData)
+	tamperedEnvelopeData[len(tamperedEnvelopeData)-1] ^= 1
+	tamperedEnvelope.EncryptedData = base64.B64Encode(tamperedEnvelopeData)
+
+	if got := decodedLen(encrypted.EncryptedData); got != len(encryptedData) {
+		t.Errorf("decodedLen() = %v, want %v", got, len(encryptedData))
+	}
+	if got := decodedLen(envelope.EncryptedData); got != len(envelopeData) {
+		t.Errorf("decodedLen() = %v, want %v", got, len(envelopeData))
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"Empty data", ""},
+		{"Plain text", "test data"},
+		{"Named AES payload", "JEFFU0tFWSQxJGFlczpkYXRh"}, // $AESKEY$1$aes:data
+		{"Invalid base64", invalidBase64.String()},
+		{"Unknown key", unknownKey.String()},
+		{"Unknown version", unknownVersion.String()},
+		{"Revoked key", revoked.String()},
+		{"Public key only", publicOnly.String()},
+		{"Tampered data", tampered.String()},
+		{"Truncated data", truncated.String()},
+		{"Tampered envelope", tamperedEnvelope.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptHardenedWithStore(store, tt.data)
+			// the very same error value, so neither its type nor its message
+			// tells the failures apart
+			if err != cryptoErrors.ErrDecryptionFailed || got != nil {
+				t.Errorf("DecryptHardenedWithStore() = %v, %v, want %v", got, err, cryptoErrors.ErrDecryptionFailed)
+			}
+		})
+	}
+}
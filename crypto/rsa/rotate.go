@@ -0,0 +1,118 @@
+package rsa
+
+import (
+	"fmt"
+
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// Rotate re-encrypts ciphertext with the latest version of the key it was
+// encrypted with.
+//
+// Parameters:
+//   - ciphertext: A value produced by Payload.String().
+//
+// Returns:
+//   - string: The rotated ciphertext, or ciphertext itself when nothing changed.
+//   - bool: True if ciphertext was re-encrypted.
+//   - error: errors.ErrKeyNotFound if the embedded key version is not loaded,
+//     or any error raised while decrypting or encrypting.
+//
+// Values that are not encrypted, or that already use the latest key version,
+// are returned unchanged. Values bound to associated data can not be rotated
+// and fail with errors.ErrAuthenticationFailed.
+func Rotate(ciphertext string) (string, bool, error) {
+	return RotateWithStore(models.DefaultKeyStore(), ciphertext)
+}
+
+// RotateWithStore behaves like Rotate but looks keys up in store instead of
+// the default store.
+func RotateWithStore(store models.KeyStore, ciphertext string) (string, bool, error) {
+	parsed := models.ParsePayload(ciphertext)
+	// named AES payloads are rotated by aes.Rotate
+	if parsed == nil || !isRSAKeyType(parsed.KeyType) {
+		return ciphertext, false, nil
+	}
+	keyName, keyVersion := parsed.KeyName, parsed.KeyVersion
+
+	if _, err := models.FindKey(store, keyName, keyVersion); err != nil {
+		return ciphertext, false, err
+	}
+	latest := models.LookupKey(store, keyName, 0)
+	if latest == nil || latest.GetVersion() <= keyVersion {
+		return ciphertext, false, nil
+	}
+
+	payload, err := DecryptWithStore(store, ciphertext)
+	if err != nil {
+		return ciphertext, false, err
+	}
+	// keep the format the value was originally encrypted with
+	encryptFn := encryptWithKey
+	if payload.KeyType == models.KeyTypeRSAEnvelope {
+		encryptFn = encryptEnvelopeWithKey
+	}
+	rotated, err := encryptFn(latest, []byte(payload.Data), nil)
+	if err != nil {
+		return ciphertext, false, err
+	}
+	return rotated.String(), true, nil
+}
+
+// RotateDocument walks doc and rotates every encrypted string leaf in place,
+// descending into nested maps and slices.
+//
+// Returns the number of values that were re-encrypted. Rotation stops at the
+// first error, leaving the values rotated so far in place.
+func RotateDocument(doc map[string]any) (int, error) {
+	return RotateDocumentWithStore(models.DefaultKeyStore(), doc)
+}
+
+// RotateDocumentWithStore behaves like RotateDocument but looks keys up in
+// store instead of the default store.
+func RotateDocumentWithStore(store models.KeyStore, doc map[string]any) (int, error) {
+	return RotateDocumentFunc(doc, func(ciphertext string) (string, bool, error) {
+		return RotateWithStore(store, ciphertext)
+	})
+}
+
+// RotateDocumentFunc walks doc like RotateDocument but rotates every string
+// leaf with rotate, e.g. to also rotate values encrypted with named secrets.
+func RotateDocumentFunc(doc map[string]any, rotate func(string) (string, bool, error)) (int, error) {
+	rotated := 0
+	for key, val := range doc {
+		newVal, n, err := rotateValue(rotate, val)
+		rotated += n
+		if err != nil {
+			return rotated, fmt.Errorf("%s: %w", key, err)
+		}
+		doc[key] = newVal
+	}
+	return rotated, nil
+}
+
+func rotateValue(rotate func(string) (string, bool, error), val any) (any, int, error) {
+	switch v := val.(type) {
+	case string:
+		rotated, ok, err := rotate(v)
+		if err != nil || !ok {
+			return v, 0, err
+		}
+		return rotated, 1, nil
+	case map[string]any:
+		n, err := RotateDocumentFunc(v, rotate)
+		return v, n, err
+	case []any:
+		rotated := 0
+		for i, item := range v {
+			newItem, n, err := rotateValue(rotate, item)
+			rotated += n
+			if err != nil {
+				return v, rotated, fmt.Errorf("[%d]: %w", i, err)
+			}
+			v[i] = newItem
+		}
+		return v, rotated, nil
+	}
+	return val, 0, nil
+}
@@ -0,0 +1,102 @@
+package rsa
+
+import (
+	"testing"
+
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func TestRotateWithStore(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "ROTATEKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	v1, err := EncryptWithStore(store, "ROTATEKEY", []byte("test data"))
+	if err != nil {
+		t.Fatalf("EncryptWithStore() error = %v", err)
+	}
+
+	got, rotated, err := RotateWithStore(store, v1.String())
+	if err != nil || rotated || got != v1.String() {
+		t.Errorf("RotateWithStore() = %v, %v, %v, want unchanged", got, rotated, err)
+	}
+
+	if err := store.Put(testutil.NewKeyInfo(t, "ROTATEKEY", 2)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, rotated, err = RotateWithStore(store, v1.String())
+	if err != nil || !rotated {
+		t.Fatalf("RotateWithStore() = %v, %v, %v, want rotated", got, rotated, err)
+	}
+	payload, err := DecryptWithStore(store, got)
+	if err != nil {
+		t.Fatalf("DecryptWithStore() error = %v", err)
+	}
+	if payload.KeyVersion != 2 || payload.Data != "test data" {
+		t.Errorf("DecryptWithStore() = %v, want version 2 with test data", payload)
+	}
+
+	got, rotated, err = RotateWithStore(store, "plain text")
+	if err != nil || rotated || got != "plain text" {
+		t.Errorf("RotateWithStore() = %v, %v, %v, want plain text unchanged", got, rotated, err)
+	}
+
+	store.Delete("ROTATEKEY", 1)
+	if _, _, err := RotateWithStore(store, v1.String()); err == nil {
+		t.Errorf("RotateWithStore() error = nil, want key not found")
+	}
+}
+
+func TestRotateDocumentWithStore(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "ROTATEKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	encrypt := func(data string) string {
+		payload, err := EncryptWithStore(store, "ROTATEKEY", []byte(data))
+		if err != nil {
+			t.Fatalf("EncryptWithStore() error = %v", err)
+		}
+		return payload.String()
+	}
+
+	doc := map[string]any{
+		"email": encrypt("a@b.c"),
+		"name":  "plain",
+		"age":   42,
+		"address": map[string]any{
+			"street": encrypt("street"),
+		},
+		"phones": []any{encrypt("123"), "456", map[string]any{"work": encrypt("789")}},
+	}
+	if err := store.Put(testutil.NewKeyInfo(t, "ROTATEKEY", 2)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	rotated, err := RotateDocumentWithStore(store, doc)
+	if err != nil {
+		t.Fatalf("RotateDocumentWithStore() error = %v", err)
+	}
+	if rotated != 4 {
+		t.Errorf("RotateDocumentWithStore() = %v, want 4", rotated)
+	}
+
+	for _, val := range []any{
+		doc["email"],
+		doc["address"].(map[string]any)["street"],
+		doc["phones"].([]any)[0],
+		doc["phones"].([]any)[2].(map[string]any)["work"],
+	} {
+		payload, err := DecryptWithStore(store, val.(string))
+		if err != nil {
+			t.Fatalf("DecryptWithStore() error = %v", err)
+		}
+		if payload.KeyVersion != 2 {
+			t.Errorf("DecryptWithStore() version = %v, want 2", payload.KeyVersion)
+		}
+	}
+	if doc["name"] != "plain" || doc["phones"].([]any)[1] != "456" || doc["age"] != 42 {
+		t.Errorf("RotateDocumentWithStore() modified plain values: %v", doc)
+	}
+}
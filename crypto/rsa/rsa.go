@@ -1,14 +1,13 @@
 package rsa
 
 import (
-	"strconv"
-
 	"github.com/nected/go-lib/crypto/base64"
 	"github.com/nected/go-lib/crypto/errors"
 	"github.com/nected/go-lib/crypto/models"
 )
 
-// Encrypt encrypts the given data using the specified encryption key.
+// Encrypt encrypts the given data using the latest active version of the
+// specified encryption key.
 // If the data is already encrypted, it returns the data as is.
 // If the key is not found, it returns the data as a string without encryption.
 //
@@ -20,6 +19,26 @@ import (
 //   - *models.Payload: A payload containing the original data and the encrypted data.
 //   - error: An error if the encryption process fails.
 func Encrypt(keyName string, data []byte) (*models.Payload, error) {
+	return EncryptWithStore(models.DefaultKeyStore(), keyName, data)
+}
+
+// EncryptWithStore behaves like Encrypt but looks the key up in store instead
+// of the default store.
+func EncryptWithStore(store models.KeyStore, keyName string, data []byte) (*models.Payload, error) {
+	return encrypt(store, keyName, data, nil, encryptWithKey)
+}
+
+// EncryptWithAAD behaves like Encrypt but binds the ciphertext to
+// additionalData, e.g. a tenant or record ID, so it can only be decrypted
+// with DecryptWithAAD and the same additionalData.
+func EncryptWithAAD(keyName string, data, additionalData []byte) (*models.Payload, error) {
+	return encrypt(models.DefaultKeyStore(), keyName, data, additionalData, encryptWithKey)
+}
+
+// keyEncryptFunc encrypts data with a resolved key in one of the RSA formats.
+type keyEncryptFunc func(keyInfo *models.KeyInfo, data, additionalData []byte) (*models.Payload, error)
+
+func encrypt(store models.KeyStore, keyName string, data, additionalData []byte, encryptFn keyEncryptFunc) (*models.Payload, error) {
 	if alreadyEncrypted(data) {
 		return &models.Payload{
 			Data:             string(data),
@@ -27,7 +46,7 @@ func Encrypt(keyName string, data []byte) (*models.Payload, error) {
 			AlreadyEncrypted: true,
 		}, nil
 	}
-	keyInfo := models.GetEncryptionKey(keyName, 0)
+	keyInfo := models.LookupKey(store, keyName, 0)
 	if keyInfo == nil {
 		// if key not found return stringfied data
 		return &models.Payload{
@@ -36,24 +55,39 @@ func Encrypt(keyName string, data []byte) (*models.Payload, error) {
 		}, nil
 	}
 
-	encryptedData, err := keyInfo.Encrypt(data)
+	return encryptFn(keyInfo, data, additionalData)
+}
+
+// encryptWithKey encrypts data with keyInfo without checking whether data is
+// already encrypted.
+func encryptWithKey(keyInfo *models.KeyInfo, data, additionalData []byte) (*models.Payload, error) {
+	encryptedData, err := keyInfo.EncryptWithAAD(data, additionalData)
 	if err != nil {
 		return nil, err
 	}
 
 	encryptedDataString := base64.B64Encode(encryptedData)
 
+	keyType := models.KeyTypeRSA
+	if keyInfo.GetAlgorithm() != models.KeyAlgorithmRSA {
+		keyType = models.KeyTypeECIES
+	}
+
 	return &models.Payload{
-		KeyName:       keyName,
-		KeyVersion:    keyInfo.GetVersion(),
-		KeyType:       models.KeyTypeRSA,
-		Data:          string(data),
-		EncryptedData: encryptedDataString,
+		KeyName:        keyInfo.GetName(),
+		KeyVersion:     keyInfo.GetVersion(),
+		KeyType:        keyType,
+		Data:           string(data),
+		EncryptedData:  encryptedDataString,
+		AssociatedData: string(additionalData),
 	}, nil
 }
 
 // Decrypt decrypts the given base64-encoded data string and returns a Payload object.
-// If the data is not encrypted, it returns the data as is.
+// If the data is not encrypted, or its key is not loaded, it returns the data
+// as is; use DecryptStrict to fail instead.
+// If the key version is revoked, it returns errors.ErrKeyRevoked, and if it was
+// loaded from a public key or certificate, an *errors.NoPrivateKeyError.
 //
 // Parameters:
 //   - data: A base64-encoded string that may contain encrypted data.
@@ -64,14 +98,46 @@ func Encrypt(keyName string, data []byte) (*models.Payload, error) {
 //
 // The function performs the following steps:
 //  1. Checks if the input data string is empty and returns nil if true.
-//  2. Decodes the base64-encoded input data.
-//  3. Checks if the decoded data is not encrypted and returns it as is if true.
-//  4. Parses the decoded data to extract keyName, keyVersion, and encryptedData.
-//  5. Retrieves the encryption key information using the keyName.
-//  6. Decodes the base64-encoded encryptedData.
-//  7. Decrypts the encryptedData using the retrieved key information.
-//  8. Constructs and returns a Payload object containing the decrypted data and other relevant information.
+//  2. Parses the input with models.ParsePayload to extract keyName, keyVersion, key type and encryptedData.
+//  3. Returns the input as is if it is not an RSA payload, e.g. plain text or a named AES payload.
+//  4. Retrieves the encryption key information using the keyName.
+//  5. Base64-decodes encryptedData.
+//  6. Decrypts the encryptedData with the legacy chunked or the envelope format.
+//  7. Constructs and returns a Payload object containing the decrypted data and other relevant information.
 func Decrypt(data string) (*models.Payload, error) {
+	return DecryptWithStore(models.DefaultKeyStore(), data)
+}
+
+// DecryptWithStore behaves like Decrypt but looks the key up in store instead
+// of the default store.
+func DecryptWithStore(store models.KeyStore, data string) (*models.Payload, error) {
+	return decrypt(store, data, nil, false)
+}
+
+// DecryptStrict behaves like Decrypt but never returns its input unchanged.
+// It fails with errors.ErrMalformedHeader when data is not an RSA or ECIES
+// payload and with a *errors.KeyError wrapping errors.ErrKeyNotFound or
+// errors.ErrVersionNotFound when the key version is not loaded.
+func DecryptStrict(data string) (*models.Payload, error) {
+	return DecryptStrictWithStore(models.DefaultKeyStore(), data)
+}
+
+// DecryptStrictWithStore behaves like DecryptStrict but looks the key up in
+// store instead of the default store.
+func DecryptStrictWithStore(store models.KeyStore, data string) (*models.Payload, error) {
+	return decrypt(store, data, nil, true)
+}
+
+// DecryptWithAAD decrypts data produced by EncryptWithAAD or
+// EncryptEnvelopeWithAAD. It returns errors.ErrAuthenticationFailed when
+// additionalData does not match the one used to encrypt.
+func DecryptWithAAD(data string, additionalData []byte) (*models.Payload, error) {
+	return decrypt(models.DefaultKeyStore(), data, additionalData, false)
+}
+
+// decrypt implements the Decrypt functions. In strict mode data that can not
+// be decrypted is an error instead of being returned as is.
+func decrypt(store models.KeyStore, data string, additionalData []byte, strict bool) (*models.Payload, error) {
 	p := models.Payload{
 		Data: data,
 	}
@@ -79,97 +145,58 @@ func Decrypt(data string) (*models.Payload, error) {
 		return nil, errors.ErrEmptyData
 	}
 
-	// decode data
-	decodedData, err := base64.B64Decode(data)
-	if err != nil {
-		p.Data = data
-		return &p, nil
-	}
-
 	// split data into keyName, keyVersion and encryptedData
 	// $keyName$keyVersion$encryptedData
-	keyName, keyVersion, encryptedData := parseData(decodedData)
+	parsed := models.ParsePayload(data)
 
-	if keyName == "" || keyVersion == 0 || encryptedData == "" {
+	// named AES payloads and signatures are not ours to decrypt
+	if parsed == nil || !isRSAKeyType(parsed.KeyType) {
+		if strict {
+			return nil, errors.ErrMalformedHeader
+		}
 		return &p, nil
 	}
+	keyName, keyVersion, keyType := parsed.KeyName, parsed.KeyVersion, parsed.KeyType
 
-	keyInfo := models.GetEncryptionKey(keyName, keyVersion)
-
-	if keyInfo == nil {
+	keyInfo, err := models.FindKey(store, keyName, keyVersion)
+	if err != nil {
+		if strict {
+			return nil, err
+		}
 		return &p, nil
 	}
 
-	encryptedData, err = base64.B64Decode(encryptedData)
+	if !keyInfo.CanDecrypt() {
+		return nil, &errors.KeyError{KeyName: keyName, KeyVersion: keyVersion, Err: errors.ErrKeyRevoked}
+	}
+
+	encryptedData, err := base64.B64Decode(parsed.EncryptedData)
 
 	if err != nil {
 		return nil, err
 	}
 
-	decryptedData, err := keyInfo.Decrypt([]byte(encryptedData))
+	var decryptedData []byte
+	switch keyType {
+	case models.KeyTypeRSAEnvelope:
+		decryptedData, err = decryptEnvelopeWithKey(keyInfo, []byte(encryptedData), additionalData)
+	default:
+		decryptedData, err = keyInfo.DecryptWithAAD([]byte(encryptedData), additionalData)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.Payload{
-		KeyName:       keyName,
-		KeyVersion:    keyVersion,
-		KeyType:       models.KeyTypeRSA,
-		Data:          string(decryptedData),
-		EncryptedData: base64.B64Encode([]byte(encryptedData)),
+		KeyName:        keyName,
+		KeyVersion:     keyVersion,
+		KeyType:        keyType,
+		Data:           string(decryptedData),
+		EncryptedData:  base64.B64Encode([]byte(encryptedData)),
+		AssociatedData: string(additionalData),
 	}, nil
 }
 
-// parseData parses a string containing key name, key version, and encrypted data
-// separated by '$' characters. It returns the key name, key version, and encrypted data.
-//
-// The input string is expected to be in the format: "$keyName$keyVersion$encryptedData".
-//
-// Parameters:
-// - data: A string containing the key name, key version, and encrypted data.
-//
-// Returns:
-// - keyName: The extracted key name.
-// - keyVersion: The extracted key version.
-// - encryptedData: The extracted encrypted data.
-func parseData(data string) (string, int, string) {
-	keyName := ""
-	keyVersion := 0
-	encryptedData := ""
-
-	var err error
-
-	if len(data) == 0 {
-		return keyName, keyVersion, encryptedData
-	}
-
-	// if data is not encrypted return as is
-	if data[0] != '$' {
-		encryptedData = data
-		return keyName, keyVersion, encryptedData
-	}
-
-	for i := 1; i < len(data); i++ {
-		if data[i] == '$' {
-			if keyName == "" {
-				keyName = data[1:i]
-				continue
-			}
-			if keyVersion == 0 {
-				keyVersionStr := data[len(keyName)+2 : i]
-				if keyVersion, err = strconv.Atoi(keyVersionStr); err != nil {
-					keyVersion = 0
-					break
-				}
-				encryptedData = data[i+1:]
-				break
-			}
-		}
-	}
-
-	return keyName, keyVersion, encryptedData
-}
-
 // alreadyEncrypted checks if the provided data is already encrypted.
 // It attempts to decode the data from base64 and then parse it to extract a key name.
 // If the key name is not empty, it returns true, indicating that the data is encrypted.
@@ -181,10 +208,10 @@ func parseData(data string) (string, int, string) {
 // Returns:
 // - bool: True if the data is already encrypted, false otherwise.
 func alreadyEncrypted(data []byte) bool {
-	decodedData, err := base64.B64Decode(string(data))
-	if err != nil {
-		return false
-	}
-	keyName, keyVersion, encryptedData := parseData(decodedData)
-	return keyName != "" && keyVersion != 0 && encryptedData != ""
+	return models.ParsePayload(string(data)) != nil
+}
+
+// isRSAKeyType reports whether keyType is produced by this package.
+func isRSAKeyType(keyType models.KeyType) bool {
+	return keyType == models.KeyTypeRSA || keyType == models.KeyTypeRSAEnvelope || keyType == models.KeyTypeECIES
 }
@@ -1,16 +1,25 @@
 package rsa
 
 import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/nected/go-lib/crypto/base64"
 	"github.com/nected/go-lib/crypto/config"
-	"github.com/nected/go-lib/crypto/errors"
+	cryptoErrors "github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
 	"github.com/nected/go-lib/crypto/models"
 )
 
@@ -65,42 +74,6 @@ func setupSuite(t *testing.T) func(t *testing.T) {
 
 }
 
-func Test_parseData(t *testing.T) {
-	type args struct {
-		data string
-	}
-	tests := []struct {
-		name  string
-		args  args
-		want  string
-		want1 int
-		want2 string
-	}{
-		{
-			name: "Test 1",
-			args: args{
-				data: "$keyName$1$encryptedData",
-			},
-			want:  "keyName",
-			want1: 1,
-			want2: "encryptedData",
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, got1, got2 := parseData(tt.args.data)
-			if got != tt.want {
-				t.Errorf("parseData() got = %v, want %v", got, tt.want)
-			}
-			if got1 != tt.want1 {
-				t.Errorf("parseData() got1 = %v, want %v", got1, tt.want1)
-			}
-			if got2 != tt.want2 {
-				t.Errorf("parseData() got2 = %v, want %v", got2, tt.want2)
-			}
-		})
-	}
-}
 func TestEncrypt(t *testing.T) {
 	teardownSuite := setupSuite(t)
 	config.LoadKeysFromEnv()
@@ -233,7 +206,7 @@ func TestDecrypt(t *testing.T) {
 			},
 			want:    nil,
 			wantErr: true,
-			err:     errors.ErrEmptyData,
+			err:     cryptoErrors.ErrEmptyData,
 		},
 		{
 			name: "Not encrypted data",
@@ -252,7 +225,7 @@ func TestDecrypt(t *testing.T) {
 			},
 			want:    nil,
 			wantErr: true,
-			err:     errors.ErrInvalidData,
+			err:     cryptoErrors.ErrInvalidData,
 		},
 		{
 			name: "Valid key version 1",
@@ -326,7 +299,7 @@ func TestDecrypt(t *testing.T) {
 				t.Errorf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if tt.wantErr && err != tt.err {
+			if tt.wantErr && !errors.Is(err, tt.err) {
 				t.Errorf("Decrypt() error = %v, wantErr %v", err, tt.err)
 				return
 			}
@@ -341,3 +314,269 @@ func TestDecrypt(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyStates(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	for version := 1; version <= 3; version++ {
+		if err := store.Put(testutil.NewKeyInfo(t, "STATEKEY", version)); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	encrypted := make(map[int]string)
+	for version := 1; version <= 3; version++ {
+		payload, err := encryptWithKey(store.Get("STATEKEY", version), []byte("test data"), nil)
+		if err != nil {
+			t.Fatalf("encryptWithKey() error = %v", err)
+		}
+		encrypted[version] = payload.String()
+	}
+
+	if err := models.SetKeyState(store, "STATEKEY", 3, models.KeyStateDecryptOnly); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if err := models.SetKeyState(store, "STATEKEY", 1, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if err := models.SetKeyState(store, "STATEKEY", 4, models.KeyStateRevoked); !errors.Is(err, cryptoErrors.ErrKeyNotFound) {
+		t.Errorf("SetKeyState() error = %v, want %v", err, cryptoErrors.ErrKeyNotFound)
+	}
+
+	// encryption skips the decrypt-only version 3
+	payload, err := EncryptWithStore(store, "STATEKEY", []byte("test data"))
+	if err != nil {
+		t.Fatalf("EncryptWithStore() error = %v", err)
+	}
+	if payload.KeyVersion != 2 {
+		t.Errorf("EncryptWithStore() version = %v, want 2", payload.KeyVersion)
+	}
+
+	// decrypt-only keys still decrypt
+	got, err := DecryptWithStore(store, encrypted[3])
+	if err != nil || got.Data != "test data" {
+		t.Errorf("DecryptWithStore() = %v, %v, want test data", got, err)
+	}
+
+	// revoked keys refuse to decrypt
+	if _, err := DecryptWithStore(store, encrypted[1]); !errors.Is(err, cryptoErrors.ErrKeyRevoked) {
+		t.Errorf("DecryptWithStore() error = %v, want %v", err, cryptoErrors.ErrKeyRevoked)
+	}
+
+	// no active version left means no encryption
+	if err := models.SetKeyState(store, "STATEKEY", 2, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	payload, err = EncryptWithStore(store, "STATEKEY", []byte("test data"))
+	if err != nil {
+		t.Fatalf("EncryptWithStore() error = %v", err)
+	}
+	if payload.KeyName != "" || payload.EncryptedData != "test data" {
+		t.Errorf("EncryptWithStore() = %v, want unencrypted data", payload)
+	}
+}
+
+func TestLoadKeysFromEnvKeyState(t *testing.T) {
+	privateKey := generatePrivateKey()
+	os.Setenv(fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "ENVSTATEKEY_1_REVOKED"), privateKey)
+	os.Setenv(fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "ENVSTATEKEY_2_decryptonly"), privateKey)
+	os.Setenv(fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "ENVSTATEKEY_3_UNKNOWN"), privateKey)
+	defer os.Unsetenv(fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "ENVSTATEKEY_1_REVOKED"))
+	defer os.Unsetenv(fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "ENVSTATEKEY_2_decryptonly"))
+	defer os.Unsetenv(fmt.Sprintf("%s_%s", config.KEY_ENV_PREFIX, "ENVSTATEKEY_3_UNKNOWN"))
+
+	store := models.NewMemoryKeyStore()
+	if err := config.LoadKeysFromEnvToStore(store); err != nil {
+		t.Fatalf("LoadKeysFromEnvToStore() error = %v", err)
+	}
+	if got := store.Get("ENVSTATEKEY", 1); got == nil || got.GetState() != models.KeyStateRevoked {
+		t.Errorf("ENVSTATEKEY version 1 = %v, want REVOKED", got)
+	}
+	if got := store.Get("ENVSTATEKEY", 2); got == nil || got.GetState() != models.KeyStateDecryptOnly {
+		t.Errorf("ENVSTATEKEY version 2 = %v, want DECRYPTONLY", got)
+	}
+	if got := store.Get("ENVSTATEKEY", 3); got != nil {
+		t.Errorf("ENVSTATEKEY version 3 loaded with an invalid state")
+	}
+}
+
+func TestEncryptWithAAD(t *testing.T) {
+	keyInfo := testutil.NewKeyInfo(t, "AADKEY", 1)
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(keyInfo); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	for _, encryptFn := range []keyEncryptFunc{encryptWithKey, encryptEnvelopeWithKey} {
+		got, err := encrypt(store, "AADKEY", []byte("test data"), []byte("record-1"), encryptFn)
+		if err != nil {
+			t.Fatalf("encrypt() error = %v", err)
+		}
+
+		payload, err := decrypt(store, got.String(), []byte("record-1"), false)
+		if err != nil || payload.Data != "test data" || payload.AssociatedData != "record-1" {
+			t.Errorf("decrypt() = %v, %v, want test data bound to record-1", payload, err)
+		}
+
+		for _, aad := range [][]byte{[]byte("record-2"), nil} {
+			if _, err := decrypt(store, got.String(), aad, false); !errors.Is(err, cryptoErrors.ErrAuthenticationFailed) {
+				t.Errorf("decrypt(%s) error = %v, want %v", got.KeyType, err, cryptoErrors.ErrAuthenticationFailed)
+			}
+		}
+	}
+
+	// ciphertexts without associated data do not decrypt with one
+	got, err := EncryptWithStore(store, "AADKEY", []byte("test data"))
+	if err != nil {
+		t.Fatalf("EncryptWithStore() error = %v", err)
+	}
+	if _, err := decrypt(store, got.String(), []byte("record-1"), false); !errors.Is(err, cryptoErrors.ErrAuthenticationFailed) {
+		t.Errorf("decrypt() error = %v, want %v", err, cryptoErrors.ErrAuthenticationFailed)
+	}
+}
+
+func TestPublicKeyOnly(t *testing.T) {
+	keyInfo := testutil.NewKeyInfo(t, "PUBKEY", 1)
+	store := models.NewMemoryKeyStore()
+	publicStore := models.NewMemoryKeyStore()
+	if err := store.Put(keyInfo); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := publicStore.Put(models.KeyInfo{Name: "PUBKEY", Version: 1, PubKey: keyInfo.GetPubKey()}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	for _, encryptFn := range []keyEncryptFunc{encryptWithKey, encryptEnvelopeWithKey} {
+		got, err := encrypt(publicStore, "PUBKEY", []byte("test data"), nil, encryptFn)
+		if err != nil {
+			t.Fatalf("encrypt() error = %v", err)
+		}
+
+		payload, err := DecryptWithStore(store, got.String())
+		if err != nil || payload.Data != "test data" {
+			t.Errorf("DecryptWithStore() = %v, %v, want test data", payload, err)
+		}
+
+		_, err = DecryptWithStore(publicStore, got.String())
+		var noPrivateKeyErr *cryptoErrors.NoPrivateKeyError
+		if !errors.As(err, &noPrivateKeyErr) || noPrivateKeyErr.KeyName != "PUBKEY" || !errors.Is(err, cryptoErrors.ErrNoPrivateKey) {
+			t.Errorf("DecryptWithStore(%s) error = %v, want *NoPrivateKeyError", got.KeyType, err)
+		}
+	}
+}
+
+func TestEllipticCurveKeys(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	xKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	store := models.NewMemoryKeyStore()
+	for _, keyInfo := range []models.KeyInfo{
+		{Name: "ECKEY", Version: 1, Algorithm: models.KeyAlgorithmECDSA, PrivateKey: ecKey, PublicKey: &ecKey.PublicKey},
+		{Name: "XKEY", Version: 1, Algorithm: models.KeyAlgorithmX25519, PrivateKey: xKey, PublicKey: xKey.PublicKey()},
+		{Name: "EDKEY", Version: 1, Algorithm: models.KeyAlgorithmEd25519, PrivateKey: edKey, PublicKey: edKey.Public()},
+	} {
+		if err := store.Put(keyInfo); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	for _, keyName := range []string{"ECKEY", "XKEY"} {
+		t.Run(keyName, func(t *testing.T) {
+			got, err := EncryptWithStore(store, keyName, []byte("test data"))
+			if err != nil {
+				t.Fatalf("EncryptWithStore() error = %v", err)
+			}
+			if got.KeyType != models.KeyTypeECIES {
+				t.Errorf("EncryptWithStore() key type = %v, want %v", got.KeyType, models.KeyTypeECIES)
+			}
+			payload, err := DecryptWithStore(store, got.String())
+			if err != nil || payload.Data != "test data" {
+				t.Errorf("DecryptWithStore() = %v, %v, want test data", payload, err)
+			}
+
+			got, err = EncryptEnvelopeWithStore(store, keyName, []byte("envelope data"))
+			if err != nil {
+				t.Fatalf("EncryptEnvelopeWithStore() error = %v", err)
+			}
+			payload, err = DecryptWithStore(store, got.String())
+			if err != nil || payload.Data != "envelope data" {
+				t.Errorf("DecryptWithStore() = %v, %v, want envelope data", payload, err)
+			}
+
+			var encrypted, decrypted bytes.Buffer
+			if err := EncryptStreamWithStore(store, &encrypted, strings.NewReader("stream data"), keyName); err != nil {
+				t.Fatalf("EncryptStreamWithStore() error = %v", err)
+			}
+			if err := DecryptStreamWithStore(store, &decrypted, &encrypted); err != nil || decrypted.String() != "stream data" {
+				t.Errorf("DecryptStreamWithStore() = %q, %v, want stream data", decrypted.String(), err)
+			}
+		})
+	}
+
+	if _, err := EncryptWithStore(store, "EDKEY", []byte("test data")); !errors.Is(err, cryptoErrors.ErrUnsupportedKeyAlgorithm) {
+		t.Errorf("EncryptWithStore() error = %v, want %v", err, cryptoErrors.ErrUnsupportedKeyAlgorithm)
+	}
+}
+
+func TestDecryptStrictWithStore(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "STRICTKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	encrypted, err := EncryptWithStore(store, "STRICTKEY", []byte("test data"))
+	if err != nil {
+		t.Fatalf("EncryptWithStore() error = %v", err)
+	}
+	unknownVersion := *encrypted
+	unknownVersion.KeyVersion = 2
+	unknownKey := *encrypted
+	unknownKey.KeyName = "OTHERKEY"
+	truncated := *encrypted
+	encryptedData, _ := base64.B64Decode(encrypted.EncryptedData)
+	truncated.EncryptedData = base64.B64Encode([]byte(encryptedData[:len(encryptedData)-1]))
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"Empty data", "", cryptoErrors.ErrEmptyData},
+		{"Plain text", "test data", cryptoErrors.ErrMalformedHeader},
+		{"Named AES payload", "JEFFU0tFWSQxJGFlczpkYXRh", cryptoErrors.ErrMalformedHeader}, // $AESKEY$1$aes:data
+		{"Unknown key", unknownKey.String(), cryptoErrors.ErrKeyNotFound},
+		{"Unknown version", unknownVersion.String(), cryptoErrors.ErrVersionNotFound},
+		{"Truncated data", truncated.String(), cryptoErrors.ErrTruncatedData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecryptStrictWithStore(store, tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("DecryptStrictWithStore() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	payload, err := DecryptStrictWithStore(store, encrypted.String())
+	if err != nil || payload.Data != "test data" {
+		t.Errorf("DecryptStrictWithStore() = %v, %v, want test data", payload, err)
+	}
+	// the lenient mode keeps returning undecryptable data as is
+	if payload, err := DecryptWithStore(store, unknownVersion.String()); err != nil || payload.Data != unknownVersion.String() {
+		t.Errorf("DecryptWithStore() = %v, %v, want the input", payload, err)
+	}
+
+	_, err = DecryptStrictWithStore(store, unknownVersion.String())
+	var keyErr *cryptoErrors.KeyError
+	if !errors.As(err, &keyErr) || keyErr.KeyName != "STRICTKEY" || keyErr.KeyVersion != 2 {
+		t.Errorf("DecryptStrictWithStore() error = %v, want *KeyError for STRICTKEY version 2", err)
+	}
+	if !errors.Is(err, cryptoErrors.ErrKeyNotFound) {
+		t.Errorf("DecryptStrictWithStore() error = %v, want it to match %v", err, cryptoErrors.ErrKeyNotFound)
+	}
+}
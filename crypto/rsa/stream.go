@@ -0,0 +1,110 @@
+package rsa
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/nected/go-lib/crypto/aes"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// RSA stream format
+//
+//	header_len(2) || "$keyName$keyVersion$" || wrapped_data_key || aes_stream
+//
+// The data key is a random AES-256 key encrypted with the RSA key, and
+// aes_stream is the output of aes.EncryptStream under that data key.
+
+// EncryptStream encrypts everything read from r with a random data key,
+// wraps the data key with the latest active version of the specified RSA key
+// and writes the result to w.
+//
+// Parameters:
+//   - w: The writer that receives the encrypted stream.
+//   - r: The reader providing the data to encrypt.
+//   - keyName: The name of the encryption key to use.
+//
+// Returns:
+//   - error: errors.ErrKeyNotFound if the key is not loaded, or an error if
+//     reading, encrypting or writing fails.
+func EncryptStream(w io.Writer, r io.Reader, keyName string) error {
+	return EncryptStreamWithStore(models.DefaultKeyStore(), w, r, keyName)
+}
+
+// EncryptStreamWithStore behaves like EncryptStream but looks the key up in
+// store instead of the default store.
+func EncryptStreamWithStore(store models.KeyStore, w io.Writer, r io.Reader, keyName string) error {
+	keyInfo, err := models.FindKey(store, keyName, 0)
+	if err != nil {
+		return err
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return err
+	}
+	wrappedDataKey, err := keyInfo.Encrypt(dataKey)
+	if err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("$%s$%v$", keyInfo.GetName(), keyInfo.GetVersion())
+	headerLen := make([]byte, 2)
+	binary.BigEndian.PutUint16(headerLen, uint16(len(header)))
+	for _, part := range [][]byte{headerLen, []byte(header), wrappedDataKey} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+
+	return aes.EncryptStream(w, r, string(dataKey))
+}
+
+// DecryptStream decrypts a stream produced by EncryptStream and writes the
+// plaintext to w. The key version is read from the stream header.
+//
+// As with aes.DecryptStream, the data already written to w must be discarded
+// when an error is returned.
+func DecryptStream(w io.Writer, r io.Reader) error {
+	return DecryptStreamWithStore(models.DefaultKeyStore(), w, r)
+}
+
+// DecryptStreamWithStore behaves like DecryptStream but looks the key up in
+// store instead of the default store.
+func DecryptStreamWithStore(store models.KeyStore, w io.Writer, r io.Reader) error {
+	headerLen := make([]byte, 2)
+	if _, err := io.ReadFull(r, headerLen); err != nil {
+		return fmt.Errorf("%w: stream header", errors.ErrTruncatedData)
+	}
+	header := make([]byte, binary.BigEndian.Uint16(headerLen))
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("%w: stream header", errors.ErrTruncatedData)
+	}
+
+	keyName, keyVersion, _ := models.ParseHeader(string(header))
+	if keyName == "" || keyVersion == 0 {
+		return fmt.Errorf("%w: stream header", errors.ErrMalformedHeader)
+	}
+	keyInfo, err := models.FindDecryptionKey(store, keyName, keyVersion)
+	if err != nil {
+		return err
+	}
+
+	wrappedSize, err := keyInfo.CiphertextSize(dataKeySize)
+	if err != nil {
+		return err
+	}
+	wrappedDataKey := make([]byte, wrappedSize)
+	if _, err := io.ReadFull(r, wrappedDataKey); err != nil {
+		return fmt.Errorf("%w: wrapped data key", errors.ErrTruncatedData)
+	}
+	dataKey, err := keyInfo.Decrypt(wrappedDataKey)
+	if err != nil {
+		return err
+	}
+
+	return aes.DecryptStream(w, r, string(dataKey))
+}
@@ -0,0 +1,57 @@
+package rsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	cryptoErrors "github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func TestEncryptStreamWithStore(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "STREAMKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	data := make([]byte, 200*1024)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("rand.Read() error = %v", err)
+	}
+
+	var encrypted bytes.Buffer
+	if err := EncryptStreamWithStore(store, &encrypted, bytes.NewReader(data), "STREAMKEY"); err != nil {
+		t.Fatalf("EncryptStreamWithStore() error = %v", err)
+	}
+	stream := encrypted.Bytes()
+
+	var decrypted bytes.Buffer
+	if err := DecryptStreamWithStore(store, &decrypted, bytes.NewReader(stream)); err != nil {
+		t.Fatalf("DecryptStreamWithStore() error = %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), data) {
+		t.Errorf("DecryptStreamWithStore() data mismatch")
+	}
+
+	if err := DecryptStreamWithStore(models.NewMemoryKeyStore(), &decrypted, bytes.NewReader(stream)); !errors.Is(err, cryptoErrors.ErrKeyNotFound) {
+		t.Errorf("DecryptStreamWithStore() error = %v, want %v", err, cryptoErrors.ErrKeyNotFound)
+	}
+
+	if err := DecryptStreamWithStore(store, &decrypted, bytes.NewReader(stream[:len(stream)-20])); err == nil {
+		t.Errorf("DecryptStreamWithStore() error = nil, want error for truncated stream")
+	}
+
+	if err := models.SetKeyState(store, "STREAMKEY", 1, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if err := DecryptStreamWithStore(store, &decrypted, bytes.NewReader(stream)); !errors.Is(err, cryptoErrors.ErrKeyRevoked) {
+		t.Errorf("DecryptStreamWithStore() error = %v, want %v", err, cryptoErrors.ErrKeyRevoked)
+	}
+
+	if err := EncryptStreamWithStore(store, &encrypted, bytes.NewReader(data), "MISSINGKEY"); !errors.Is(err, cryptoErrors.ErrKeyNotFound) {
+		t.Errorf("EncryptStreamWithStore() error = %v, want %v", err, cryptoErrors.ErrKeyNotFound)
+	}
+}
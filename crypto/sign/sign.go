@@ -0,0 +1,175 @@
+package sign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+
+	// register the hash functions used by the signatures
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/nected/go-lib/crypto/base64"
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+// Signature format
+//
+//	base64("$keyName$keyVersion$" + tag + ":" + base64(signature))
+//
+// where tag is "ps256" for RSA-PSS with SHA-256, "ps512" for RSA-PSS with
+// SHA-512, "es256" for ECDSA with SHA-256 and "ed25519" for Ed25519. Verify
+// reads the key version and algorithm from the signature.
+const (
+	PSSWithSHA256   = models.KeyTypeRSAPSSSHA256
+	PSSWithSHA512   = models.KeyTypeRSAPSSSHA512
+	ECDSAWithSHA256 = models.KeyTypeECDSASHA256
+	Ed25519         = models.KeyTypeEd25519
+)
+
+type signatureAlgorithm struct {
+	keyAlgorithm models.KeyAlgorithm
+	hash         crypto.Hash
+}
+
+var algorithms = map[models.KeyType]signatureAlgorithm{
+	PSSWithSHA256:   {models.KeyAlgorithmRSA, crypto.SHA256},
+	PSSWithSHA512:   {models.KeyAlgorithmRSA, crypto.SHA512},
+	ECDSAWithSHA256: {models.KeyAlgorithmECDSA, crypto.SHA256},
+	// Ed25519 hashes the message itself
+	Ed25519: {models.KeyAlgorithmEd25519, 0},
+}
+
+// defaultAlgorithms are used by Sign for each key algorithm.
+var defaultAlgorithms = map[models.KeyAlgorithm]models.KeyType{
+	models.KeyAlgorithmRSA:     PSSWithSHA256,
+	models.KeyAlgorithmECDSA:   ECDSAWithSHA256,
+	models.KeyAlgorithmEd25519: Ed25519,
+}
+
+// Sign signs data using the latest active version of the specified key: with
+// RSA-PSS and SHA-256 for RSA keys, ECDSA and SHA-256 for ECDSA keys and
+// Ed25519 for Ed25519 keys.
+//
+// Parameters:
+//   - keyName: The name of the signing key to use.
+//   - data: The data to sign.
+//
+// Returns:
+//   - string: The signature, carrying the key name and version.
+//   - error: errors.ErrKeyNotFound if the key is not loaded, or an error if
+//     signing fails.
+func Sign(keyName string, data []byte) (string, error) {
+	return SignWithStore(models.DefaultKeyStore(), keyName, data, "")
+}
+
+// SignWithAlgorithm behaves like Sign with an explicit algorithm, which must
+// match the algorithm of the key.
+func SignWithAlgorithm(keyName string, data []byte, algorithm models.KeyType) (string, error) {
+	return SignWithStore(models.DefaultKeyStore(), keyName, data, algorithm)
+}
+
+// SignWithStore behaves like SignWithAlgorithm but looks the key up in store
+// instead of the default store. An empty algorithm picks the default of the
+// key, like Sign.
+func SignWithStore(store models.KeyStore, keyName string, data []byte, algorithm models.KeyType) (string, error) {
+	keyInfo, err := models.FindKey(store, keyName, 0)
+	if err != nil {
+		return "", err
+	}
+	if algorithm == "" {
+		algorithm = defaultAlgorithms[keyInfo.GetAlgorithm()]
+	}
+	alg, ok := algorithms[algorithm]
+	if !ok || alg.keyAlgorithm != keyInfo.GetAlgorithm() {
+		return "", fmt.Errorf("%w: can not sign %q with %s keys", errors.ErrUnsupportedKeyAlgorithm, algorithm, keyInfo.GetAlgorithm())
+	}
+	if !keyInfo.HasPrivateKey() {
+		return "", &errors.NoPrivateKeyError{KeyName: keyInfo.GetName(), KeyVersion: keyInfo.GetVersion()}
+	}
+
+	var signature []byte
+	switch privateKey := keyInfo.GetPrivateKey().(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPSS(rand.Reader, privateKey, alg.hash, digest(alg.hash, data), nil)
+	case *ecdsa.PrivateKey:
+		signature, err = ecdsa.SignASN1(rand.Reader, privateKey, digest(alg.hash, data))
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(privateKey, data)
+	default:
+		err = fmt.Errorf("%w: %T", errors.ErrUnsupportedKeyAlgorithm, privateKey)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	p := models.Payload{
+		KeyName:       keyInfo.GetName(),
+		KeyVersion:    keyInfo.GetVersion(),
+		KeyType:       algorithm,
+		EncryptedData: base64.B64Encode(signature),
+	}
+	return p.String(), nil
+}
+
+// Verify checks that signature was produced by Sign over data.
+//
+// Decrypt-only keys still verify existing signatures; revoked keys do not.
+//
+// Returns:
+//   - error: nil if the signature is valid, errors.ErrInvalidSignature if it
+//     is malformed or does not match data, errors.ErrKeyNotFound if the key
+//     version is not loaded and errors.ErrKeyRevoked if it has been revoked.
+func Verify(signature string, data []byte) error {
+	return VerifyWithStore(models.DefaultKeyStore(), signature, data)
+}
+
+// VerifyWithStore behaves like Verify but looks the key up in store instead
+// of the default store.
+func VerifyWithStore(store models.KeyStore, signature string, data []byte) error {
+	parsed := models.ParsePayload(signature)
+	if parsed == nil {
+		return errors.ErrInvalidSignature
+	}
+	alg, ok := algorithms[parsed.KeyType]
+	if !ok {
+		return errors.ErrInvalidSignature
+	}
+
+	keyInfo, err := models.FindDecryptionKey(store, parsed.KeyName, parsed.KeyVersion)
+	if err != nil {
+		return err
+	}
+
+	sig, err := base64.B64Decode(parsed.EncryptedData)
+	if err != nil {
+		return errors.ErrInvalidSignature
+	}
+
+	valid := false
+	switch publicKey := keyInfo.GetPublicKey().(type) {
+	case *rsa.PublicKey:
+		valid = alg.keyAlgorithm == models.KeyAlgorithmRSA &&
+			rsa.VerifyPSS(publicKey, alg.hash, digest(alg.hash, data), []byte(sig), nil) == nil
+	case *ecdsa.PublicKey:
+		valid = alg.keyAlgorithm == models.KeyAlgorithmECDSA &&
+			ecdsa.VerifyASN1(publicKey, digest(alg.hash, data), []byte(sig))
+	case ed25519.PublicKey:
+		valid = alg.keyAlgorithm == models.KeyAlgorithmEd25519 &&
+			ed25519.Verify(publicKey, data, []byte(sig))
+	}
+	if !valid {
+		return errors.ErrInvalidSignature
+	}
+	return nil
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,160 @@
+package sign
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	stdErrors "errors"
+	"testing"
+
+	"github.com/nected/go-lib/crypto/errors"
+	"github.com/nected/go-lib/crypto/internal/testutil"
+	"github.com/nected/go-lib/crypto/models"
+)
+
+func TestSignWithStore(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	for version := 1; version <= 2; version++ {
+		if err := store.Put(testutil.NewKeyInfo(t, "SIGNKEY", version)); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	data := []byte(`{"event":"created"}`)
+
+	for _, algorithm := range []models.KeyType{PSSWithSHA256, PSSWithSHA512} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			signature, err := SignWithStore(store, "SIGNKEY", data, algorithm)
+			if err != nil {
+				t.Fatalf("SignWithStore() error = %v", err)
+			}
+			parsed := models.ParsePayload(signature)
+			if parsed == nil || parsed.KeyVersion != 2 || parsed.KeyType != algorithm {
+				t.Fatalf("ParsePayload() = %v, want version 2 of type %v", parsed, algorithm)
+			}
+			if err := VerifyWithStore(store, signature, data); err != nil {
+				t.Errorf("VerifyWithStore() error = %v", err)
+			}
+		})
+	}
+
+	if _, err := SignWithStore(store, "MISSING", data, PSSWithSHA256); !stdErrors.Is(err, errors.ErrKeyNotFound) {
+		t.Errorf("SignWithStore() error = %v, want %v", err, errors.ErrKeyNotFound)
+	}
+	if _, err := SignWithStore(store, "SIGNKEY", data, models.KeyTypeRSA); err == nil {
+		t.Errorf("SignWithStore() error = nil, want an unsupported algorithm error")
+	}
+}
+
+func TestVerifyWithStore(t *testing.T) {
+	store := models.NewMemoryKeyStore()
+	if err := store.Put(testutil.NewKeyInfo(t, "SIGNKEY", 1)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data := []byte("payload")
+	signature, err := SignWithStore(store, "SIGNKEY", data, PSSWithSHA256)
+	if err != nil {
+		t.Fatalf("SignWithStore() error = %v", err)
+	}
+	parsed := models.ParsePayload(signature)
+	unknown := *parsed
+	unknown.KeyVersion = 7
+	encrypted := *parsed
+	encrypted.KeyType = models.KeyTypeRSA
+
+	tests := []struct {
+		name      string
+		signature string
+		data      []byte
+		wantErr   error
+	}{
+		{"Valid", signature, data, nil},
+		{"Tampered data", signature, []byte("payload!"), errors.ErrInvalidSignature},
+		{"Not a signature", "plain text", data, errors.ErrInvalidSignature},
+		{"Encrypted payload", encrypted.String(), data, errors.ErrInvalidSignature},
+		{"Unknown version", unknown.String(), data, errors.ErrKeyNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyWithStore(store, tt.signature, tt.data); !stdErrors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyWithStore() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := models.SetKeyState(store, "SIGNKEY", 1, models.KeyStateDecryptOnly); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if err := VerifyWithStore(store, signature, data); err != nil {
+		t.Errorf("VerifyWithStore() error = %v, want decrypt-only keys to verify", err)
+	}
+	if err := models.SetKeyState(store, "SIGNKEY", 1, models.KeyStateRevoked); err != nil {
+		t.Fatalf("SetKeyState() error = %v", err)
+	}
+	if err := VerifyWithStore(store, signature, data); !stdErrors.Is(err, errors.ErrKeyRevoked) {
+		t.Errorf("VerifyWithStore() error = %v, want %v", err, errors.ErrKeyRevoked)
+	}
+}
+
+func TestSignWithStoreKeyAlgorithms(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	store := models.NewMemoryKeyStore()
+	for _, keyInfo := range []models.KeyInfo{
+		testutil.NewKeyInfo(t, "RSAKEY", 1),
+		{Name: "ECKEY", Version: 1, Algorithm: models.KeyAlgorithmECDSA, PrivateKey: ecKey, PublicKey: &ecKey.PublicKey},
+		{Name: "EDKEY", Version: 1, Algorithm: models.KeyAlgorithmEd25519, PrivateKey: edKey, PublicKey: edKey.Public()},
+	} {
+		if err := store.Put(keyInfo); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	data := []byte("payload")
+
+	tests := []struct {
+		keyName       string
+		wantAlgorithm models.KeyType
+	}{
+		{"RSAKEY", PSSWithSHA256},
+		{"ECKEY", ECDSAWithSHA256},
+		{"EDKEY", Ed25519},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyName, func(t *testing.T) {
+			signature, err := SignWithStore(store, tt.keyName, data, "")
+			if err != nil {
+				t.Fatalf("SignWithStore() error = %v", err)
+			}
+			if parsed := models.ParsePayload(signature); parsed == nil || parsed.KeyType != tt.wantAlgorithm {
+				t.Errorf("ParsePayload() = %v, want type %v", parsed, tt.wantAlgorithm)
+			}
+			if err := VerifyWithStore(store, signature, data); err != nil {
+				t.Errorf("VerifyWithStore() error = %v", err)
+			}
+			if err := VerifyWithStore(store, signature, []byte("payload!")); !stdErrors.Is(err, errors.ErrInvalidSignature) {
+				t.Errorf("VerifyWithStore() error = %v, want %v", err, errors.ErrInvalidSignature)
+			}
+		})
+	}
+
+	if _, err := SignWithStore(store, "ECKEY", data, PSSWithSHA256); !stdErrors.Is(err, errors.ErrUnsupportedKeyAlgorithm) {
+		t.Errorf("SignWithStore() error = %v, want %v", err, errors.ErrUnsupportedKeyAlgorithm)
+	}
+
+	// a signature claiming another algorithm than the key's never verifies
+	signature, err := SignWithStore(store, "EDKEY", data, "")
+	if err != nil {
+		t.Fatalf("SignWithStore() error = %v", err)
+	}
+	forged := models.ParsePayload(signature)
+	forged.KeyType = ECDSAWithSHA256
+	if err := VerifyWithStore(store, forged.String(), data); !stdErrors.Is(err, errors.ErrInvalidSignature) {
+		t.Errorf("VerifyWithStore() error = %v, want %v", err, errors.ErrInvalidSignature)
+	}
+}